@@ -0,0 +1,77 @@
+package hash
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"unsafe"
+)
+
+// DefaultSeed is the seed used when no other seed is given.
+const DefaultSeed uint64 = 0
+
+const (
+	wyp0 = 0xa0761d6478bd642f
+	wyp1 = 0xe7037ed1a0b428db
+	wyp2 = 0x8ebc6af09c88c6e3
+	wyp3 = 0x589965cc75374cc3
+)
+
+// String returns the 64-bit wyhash of s under seed.
+func String(seed uint64, s string) uint64 {
+	return Bytes(seed, unsafe.Slice(unsafe.StringData(s), len(s)))
+}
+
+// Bytes returns the 64-bit wyhash of p under seed.
+func Bytes(seed uint64, p []byte) uint64 {
+	n := len(p)
+	seed ^= wymix(seed^wyp0, wyp1)
+
+	var a, b uint64
+	switch {
+	case n == 0:
+	case n < 4:
+		a = uint64(p[0])<<16 | uint64(p[n>>1])<<8 | uint64(p[n-1])
+	case n <= 16:
+		q := (n >> 3) << 2
+		a = wyr4(p)<<32 | wyr4(p[q:])
+		b = wyr4(p[n-4:])<<32 | wyr4(p[n-4-q:])
+	default:
+		i := 0
+		if n >= 48 {
+			see1, see2 := seed, seed
+			for ; n-i >= 48; i += 48 {
+				seed = wymix(wyr8(p[i:])^wyp1, wyr8(p[i+8:])^seed)
+				see1 = wymix(wyr8(p[i+16:])^wyp2, wyr8(p[i+24:])^see1)
+				see2 = wymix(wyr8(p[i+32:])^wyp3, wyr8(p[i+40:])^see2)
+			}
+			seed ^= see1 ^ see2
+		}
+		for ; n-i > 16; i += 16 {
+			seed = wymix(wyr8(p[i:])^wyp1, wyr8(p[i+8:])^seed)
+		}
+		a = wyr8(p[n-16:])
+		b = wyr8(p[n-8:])
+	}
+
+	b, a = bits.Mul64(a^wyp1, b^seed)
+	return wymix(a^wyp0^uint64(n), b^wyp1)
+}
+
+// Uint64 returns the hash of x under seed.
+func Uint64(seed uint64, x uint64) uint64 {
+	return wymix(wymix(x^wyp0, seed^wyp1), wyp2)
+}
+
+// wymix multiplies a and b into 128 bits and folds the result.
+func wymix(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return hi ^ lo
+}
+
+func wyr8(p []byte) uint64 {
+	return binary.LittleEndian.Uint64(p)
+}
+
+func wyr4(p []byte) uint64 {
+	return uint64(binary.LittleEndian.Uint32(p))
+}
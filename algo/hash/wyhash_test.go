@@ -0,0 +1,127 @@
+package hash
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringEqualsBytes(t *testing.T) {
+	for n := 0; n < 200; n++ {
+		s := strings.Repeat("x", n)
+		assert.Equal(t, Bytes(7, []byte(s)), String(7, s), "len %d", n)
+	}
+}
+
+func TestStringReferenceVectors(t *testing.T) {
+	// the test vectors of the reference wyhash, the seed is the index.
+	tests := []struct {
+		s    string
+		hash uint64
+	}{
+		{"", 0x409638ee2bde459},
+		{"a", 0xa8412d091b5fe0a9},
+		{"abc", 0x32dd92e4b2915153},
+		{"message digest", 0x8619124089a3a16b},
+		{"abcdefghijklmnopqrstuvwxyz", 0x7a43afb61d7f5f40},
+		{"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789", 0xff42329b90e50d58},
+		{strings.Repeat("1234567890", 8), 0xc39cab13b115aad3},
+	}
+
+	for i, tt := range tests {
+		assert.Equal(t, tt.hash, String(uint64(i), tt.s), "%q", tt.s)
+	}
+}
+
+func TestString48Bytes(t *testing.T) {
+	// exactly 48 bytes go through the 48-byte loop. The reference vectors
+	// have no such input, this value was computed by this implementation
+	// once it matched them and only guards against regressions.
+	s := strings.Repeat("1234567890", 5)[:48]
+	assert.Equal(t, uint64(0xcb0a9453b5f83bdc), String(7, s))
+}
+
+func TestStringSeed(t *testing.T) {
+	assert.Equal(t, String(1, "cat"), String(1, "cat"))
+	assert.NotEqual(t, String(1, "cat"), String(2, "cat"))
+	assert.NotEqual(t, String(1, "cat"), String(1, "cat1"))
+}
+
+func TestStringAllLengths(t *testing.T) {
+	// every length goes through a different branch, flipping any byte
+	// must change the hash.
+	for n := 1; n < 200; n++ {
+		p := []byte(strings.Repeat("a", n))
+		h := Bytes(0, p)
+		for i := 0; i < n; i++ {
+			p[i] = 'b'
+			assert.NotEqual(t, h, Bytes(0, p), "len %d, byte %d", n, i)
+			p[i] = 'a'
+		}
+	}
+}
+
+func TestUint64(t *testing.T) {
+	assert.Equal(t, Uint64(1, 42), Uint64(1, 42))
+	assert.NotEqual(t, Uint64(1, 42), Uint64(2, 42))
+	assert.NotEqual(t, Uint64(1, 42), Uint64(1, 43))
+}
+
+// chainVariance puts n keys into size buckets by the low bits of hash
+// and returns the variance of the chain lengths.
+func chainVariance(size, n int, hash func(string) uint64) float64 {
+	chains := make([]int, size)
+	for i := 0; i < n; i++ {
+		chains[hash(fmt.Sprintf("key-%d", i))&uint64(size-1)]++
+	}
+
+	mean := float64(n) / float64(size)
+	variance := 0.0
+	for _, c := range chains {
+		d := float64(c) - mean
+		variance += d * d
+	}
+	return variance / float64(size)
+}
+
+func TestStringDistribution(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping distribution test in short mode")
+	}
+
+	// with load factor 1 the chain lengths of a uniform hash follow a
+	// Poisson distribution, whose variance is 1.
+	for shift := 8; shift <= 20; shift += 4 {
+		size := 1 << shift
+		v := chainVariance(size, size, func(s string) uint64 {
+			return String(DefaultSeed, s)
+		})
+		t.Logf("wyhash buckets %d variance %.3f", size, v)
+		assert.InDelta(t, 1.0, v, 0.25, "buckets %d", size)
+	}
+
+	// BKDRHash only has 249997 distinct values, bigger tables are
+	// mostly empty.
+	size := 1 << 20
+	v := chainVariance(size, size, func(s string) uint64 {
+		return uint64(BKDRHash(s))
+	})
+	t.Logf("bkdr buckets %d variance %.3f", size, v)
+	assert.True(t, v > 2)
+}
+
+func BenchmarkString(b *testing.B) {
+	s := strings.Repeat("x", 15)
+	for i := 0; i < b.N; i++ {
+		String(DefaultSeed, s)
+	}
+}
+
+func BenchmarkBKDRHash(b *testing.B) {
+	s := strings.Repeat("x", 15)
+	for i := 0; i < b.N; i++ {
+		BKDRHash(s)
+	}
+}
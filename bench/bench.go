@@ -1,17 +1,21 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"math"
 	"math/rand"
+	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/csimplestring/go-concurrent-map/algo/random"
 	"github.com/csimplestring/go-concurrent-map/ccmap"
 	"github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/csimplestring/go-concurrent-map/ccmap/lockfree"
 	"github.com/csimplestring/go-concurrent-map/ccmap/v1"
 )
 
@@ -29,14 +33,42 @@ func init() {
 	}
 }
 
+var maps = flag.String("maps", "locked,concurrent,lockfree",
+	"comma separated maps to benchmark: locked, concurrent, lockfree")
+
+// newMaps creates the maps to benchmark by name.
+var newMaps = map[string]struct {
+	title string
+	new   func() (ccmap.Map, error)
+}{
+	"locked": {"locked hash map", func() (ccmap.Map, error) {
+		return v1.NewHashMap(1024)
+	}},
+	"concurrent": {"concurrent hash map", func() (ccmap.Map, error) {
+		return v1.NewConcurrentMap(16)
+	}},
+	"lockfree": {"lock-free hash map", func() (ccmap.Map, error) {
+		return lockfree.NewMap(1024)
+	}},
+}
+
 func main() {
+	flag.Parse()
 	runtime.GOMAXPROCS(4)
-	h1, _ := v1.NewHashMap(1024)
-	h2, _ := v1.NewConcurrentMap(16)
-
-	printStat("locked hash map", suit(15, 100000, 30, 100000, h1))
-	printStat("concurrent hash map", suit(15, 100000, 30, 100000, h2))
 
+	for _, name := range strings.Split(*maps, ",") {
+		m, ok := newMaps[name]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown map %q\n", name)
+			os.Exit(2)
+		}
+		h, err := m.new()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		printStat(m.title, suit(15, 100000, 30, 100000, h))
+	}
 }
 
 func suit(wNum, wOps, rNum, rOps int, cmap ccmap.Map) []string {
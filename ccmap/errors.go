@@ -0,0 +1,19 @@
+package ccmap
+
+import "errors"
+
+// Errors returned by the maps, wrapped with details.
+// Test for them with errors.Is.
+var (
+	// ErrInvalidCapacity reports a size or capacity that is not positive,
+	// or a table size that is not a power of two.
+	ErrInvalidCapacity = errors.New("ccmap: invalid capacity")
+	// ErrCapacityExceeded reports a table that would grow beyond
+	// MAX_TABLE_SIZE buckets.
+	ErrCapacityExceeded = errors.New("ccmap: capacity exceeded")
+	// ErrInvalidConfig reports an invalid or inconsistent setting.
+	ErrInvalidConfig = errors.New("ccmap: invalid config")
+	// ErrIncompatibleMap reports a map that cannot be combined with
+	// another, e.g. because they are of different types.
+	ErrIncompatibleMap = errors.New("ccmap: incompatible map")
+)
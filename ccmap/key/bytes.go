@@ -0,0 +1,78 @@
+package key
+
+import (
+	"encoding/hex"
+
+	"github.com/csimplestring/go-concurrent-map/algo/hash"
+)
+
+// bytesKey implements Key for a byte slice.
+type bytesKey struct {
+	h int
+	b string
+}
+
+// NewBytesKey returns a new key holding a copy of b.
+func NewBytesKey(b []byte) Key {
+	return &bytesKey{
+		b: string(b),
+		h: (int)(hash.String(hash.DefaultSeed, string(b))),
+	}
+}
+
+func (b *bytesKey) Hash() int {
+	return b.h
+}
+
+func (b *bytesKey) SeededHash(seed uint64) int {
+	return (int)(hash.String(seed, b.b))
+}
+
+// Equal checks if k is a bytes key with the same content.
+func (b *bytesKey) Equal(k Key) bool {
+	other, ok := k.(*bytesKey)
+	return ok && b.b == other.b
+}
+
+// String returns the hex encoding of b.
+func (b *bytesKey) String() string {
+	return hex.EncodeToString([]byte(b.b))
+}
+
+// uuidKey implements Key for a UUID.
+type uuidKey [16]byte
+
+// NewUUIDKey returns a new key for the UUID u.
+func NewUUIDKey(u [16]byte) Key {
+	k := uuidKey(u)
+	return &k
+}
+
+func (u *uuidKey) Hash() int {
+	return u.SeededHash(hash.DefaultSeed)
+}
+
+func (u *uuidKey) SeededHash(seed uint64) int {
+	return (int)(hash.Bytes(seed, u[:]))
+}
+
+// Equal checks if k is the same UUID.
+func (u *uuidKey) Equal(k Key) bool {
+	other, ok := k.(*uuidKey)
+	return ok && *u == *other
+}
+
+// String returns u in its canonical xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx form.
+func (u *uuidKey) String() string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf)
+}
@@ -0,0 +1,46 @@
+package key
+
+import (
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBytesKeyProperties(t *testing.T) {
+	assert.NoError(t, quick.Check(func(a, b []byte) bool {
+		return checkConsistent(NewBytesKey(a), NewBytesKey(b), string(a) == string(b)) &&
+			checkConsistent(NewBytesKey(a), NewBytesKey(append([]byte(nil), a...)), true)
+	}, nil))
+}
+
+func TestBytesKeyCopies(t *testing.T) {
+	b := []byte("cat")
+	k := NewBytesKey(b)
+	b[0] = 'b'
+
+	assert.True(t, k.Equal(NewBytesKey([]byte("cat"))))
+	assert.Equal(t, "636174", k.String())
+}
+
+func TestBytesKeyCrossType(t *testing.T) {
+	assert.NoError(t, quick.Check(func(s string) bool {
+		return checkConsistent(NewBytesKey([]byte(s)), NewStringKey(s), false)
+	}, nil))
+}
+
+func TestUUIDKeyProperties(t *testing.T) {
+	assert.NoError(t, quick.Check(func(a, b [16]byte) bool {
+		return checkConsistent(NewUUIDKey(a), NewUUIDKey(b), a == b) &&
+			checkConsistent(NewUUIDKey(a), NewUUIDKey(a), true) &&
+			checkConsistent(NewUUIDKey(a), NewBytesKey(a[:]), false)
+	}, nil))
+}
+
+func TestUUIDKeyString(t *testing.T) {
+	u := [16]byte{
+		0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3,
+		0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00,
+	}
+	assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", NewUUIDKey(u).String())
+}
@@ -0,0 +1,69 @@
+package key
+
+import (
+	"strings"
+
+	"github.com/csimplestring/go-concurrent-map/algo/hash"
+)
+
+// compositeKey implements Key for a tuple of keys.
+type compositeKey struct {
+	h     int
+	parts []Key
+}
+
+// NewCompositeKey returns a key made of parts, in order. Two composite
+// keys are equal if they have the same number of parts and all of them
+// are equal.
+func NewCompositeKey(parts ...Key) Key {
+	c := &compositeKey{
+		parts: append([]Key(nil), parts...),
+	}
+	c.h = c.combine(func(k Key) int {
+		return k.Hash()
+	}, hash.DefaultSeed)
+	return c
+}
+
+func (c *compositeKey) Hash() int {
+	return c.h
+}
+
+func (c *compositeKey) SeededHash(seed uint64) int {
+	return c.combine(func(k Key) int {
+		return HashOf(k, seed)
+	}, seed)
+}
+
+// combine folds the hashes of the parts, so that their order matters.
+func (c *compositeKey) combine(hashOf func(Key) int, seed uint64) int {
+	h := uint64(len(c.parts))
+	for _, p := range c.parts {
+		h = hash.Uint64(seed, h^uint64(hashOf(p)))
+	}
+	return (int)(h)
+}
+
+// Equal checks if k is a composite key with equal parts.
+func (c *compositeKey) Equal(k Key) bool {
+	other, ok := k.(*compositeKey)
+	if !ok || len(c.parts) != len(other.parts) {
+		return false
+	}
+
+	for i, p := range c.parts {
+		if !p.Equal(other.parts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the parts of c, like (p1, p2).
+func (c *compositeKey) String() string {
+	strs := make([]string, len(c.parts))
+	for i, p := range c.parts {
+		strs[i] = p.String()
+	}
+	return "(" + strings.Join(strs, ", ") + ")"
+}
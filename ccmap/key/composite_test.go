@@ -0,0 +1,51 @@
+package key
+
+import (
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompositeKeyProperties(t *testing.T) {
+	assert.NoError(t, quick.Check(func(s1, s2 string, i1, i2 int64) bool {
+		a := NewCompositeKey(NewStringKey(s1), NewInt64Key(i1))
+		b := NewCompositeKey(NewStringKey(s2), NewInt64Key(i2))
+		return checkConsistent(a, b, s1 == s2 && i1 == i2) &&
+			checkConsistent(a, NewCompositeKey(NewStringKey(s1), NewInt64Key(i1)), true)
+	}, nil))
+}
+
+func TestCompositeKeyOrder(t *testing.T) {
+	a := NewCompositeKey(NewIntKey(1), NewIntKey(2))
+	b := NewCompositeKey(NewIntKey(2), NewIntKey(1))
+
+	assert.False(t, a.Equal(b))
+	assert.NotEqual(t, a.Hash(), b.Hash())
+	assert.NotEqual(t, HashOf(a, 7), HashOf(b, 7))
+}
+
+func TestCompositeKeyArity(t *testing.T) {
+	a := NewCompositeKey(NewIntKey(1))
+	b := NewCompositeKey(NewIntKey(1), NewIntKey(1))
+
+	assert.True(t, checkConsistent(a, b, false))
+	assert.True(t, checkConsistent(NewCompositeKey(), NewCompositeKey(), true))
+	assert.True(t, checkConsistent(a, NewIntKey(1), false))
+}
+
+func TestCompositeKeyNested(t *testing.T) {
+	a := NewCompositeKey(NewStringKey("tenant"), NewCompositeKey(NewIntKey(1), NewIntKey(2)))
+	b := NewCompositeKey(NewStringKey("tenant"), NewCompositeKey(NewIntKey(1), NewIntKey(2)))
+
+	assert.True(t, checkConsistent(a, b, true))
+	assert.Equal(t, "(tenant, (1, 2))", a.String())
+}
+
+func TestCompositeKeyCopiesParts(t *testing.T) {
+	parts := []Key{NewIntKey(1), NewIntKey(2)}
+	a := NewCompositeKey(parts...)
+	parts[0] = NewIntKey(3)
+
+	assert.True(t, a.Equal(NewCompositeKey(NewIntKey(1), NewIntKey(2))))
+}
@@ -0,0 +1,85 @@
+package key
+
+import (
+	"strconv"
+
+	"github.com/csimplestring/go-concurrent-map/algo/hash"
+)
+
+// intKey implements Key for an int.
+type intKey int
+
+// NewIntKey returns a new int key.
+func NewIntKey(i int) Key {
+	return intKey(i)
+}
+
+func (i intKey) Hash() int {
+	return i.SeededHash(hash.DefaultSeed)
+}
+
+func (i intKey) SeededHash(seed uint64) int {
+	return (int)(hash.Uint64(seed, uint64(i)))
+}
+
+// Equal checks if k is an int key of the same value.
+func (i intKey) Equal(k Key) bool {
+	other, ok := k.(intKey)
+	return ok && i == other
+}
+
+func (i intKey) String() string {
+	return strconv.Itoa(int(i))
+}
+
+// int64Key implements Key for an int64.
+type int64Key int64
+
+// NewInt64Key returns a new int64 key.
+func NewInt64Key(i int64) Key {
+	return int64Key(i)
+}
+
+func (i int64Key) Hash() int {
+	return i.SeededHash(hash.DefaultSeed)
+}
+
+func (i int64Key) SeededHash(seed uint64) int {
+	return (int)(hash.Uint64(seed, uint64(i)))
+}
+
+// Equal checks if k is an int64 key of the same value.
+func (i int64Key) Equal(k Key) bool {
+	other, ok := k.(int64Key)
+	return ok && i == other
+}
+
+func (i int64Key) String() string {
+	return strconv.FormatInt(int64(i), 10)
+}
+
+// uint64Key implements Key for an uint64.
+type uint64Key uint64
+
+// NewUint64Key returns a new uint64 key.
+func NewUint64Key(i uint64) Key {
+	return uint64Key(i)
+}
+
+func (i uint64Key) Hash() int {
+	return i.SeededHash(hash.DefaultSeed)
+}
+
+func (i uint64Key) SeededHash(seed uint64) int {
+	return (int)(hash.Uint64(seed, uint64(i)))
+}
+
+// Equal checks if k is an uint64 key of the same value.
+func (i uint64Key) Equal(k Key) bool {
+	other, ok := k.(uint64Key)
+	return ok && i == other
+}
+
+func (i uint64Key) String() string {
+	return strconv.FormatUint(uint64(i), 10)
+}
@@ -0,0 +1,65 @@
+package key
+
+import (
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// checkConsistent verifies the Key contract on a and b, which are equal
+// iff eq: Equal is reflexive and symmetric, equal keys have equal hashes
+// and strings.
+func checkConsistent(a, b Key, eq bool) bool {
+	if !a.Equal(a) || !b.Equal(b) {
+		return false
+	}
+	if a.Equal(b) != eq || b.Equal(a) != eq {
+		return false
+	}
+	if eq {
+		return a.Hash() == b.Hash() &&
+			HashOf(a, 7) == HashOf(b, 7) &&
+			a.String() == b.String()
+	}
+	return true
+}
+
+func TestIntKeyProperties(t *testing.T) {
+	assert.NoError(t, quick.Check(func(a, b int) bool {
+		return checkConsistent(NewIntKey(a), NewIntKey(b), a == b) &&
+			checkConsistent(NewIntKey(a), NewIntKey(a), true)
+	}, nil))
+
+	assert.NoError(t, quick.Check(func(a, b int64) bool {
+		return checkConsistent(NewInt64Key(a), NewInt64Key(b), a == b) &&
+			checkConsistent(NewInt64Key(a), NewInt64Key(a), true)
+	}, nil))
+
+	assert.NoError(t, quick.Check(func(a, b uint64) bool {
+		return checkConsistent(NewUint64Key(a), NewUint64Key(b), a == b) &&
+			checkConsistent(NewUint64Key(a), NewUint64Key(a), true)
+	}, nil))
+}
+
+func TestIntKeyCrossType(t *testing.T) {
+	assert.NoError(t, quick.Check(func(a int) bool {
+		return checkConsistent(NewIntKey(a), NewInt64Key(int64(a)), false) &&
+			checkConsistent(NewIntKey(a), NewUint64Key(uint64(a)), false) &&
+			checkConsistent(NewInt64Key(int64(a)), NewUint64Key(uint64(a)), false)
+	}, nil))
+}
+
+func TestIntKeyString(t *testing.T) {
+	assert.Equal(t, "-42", NewIntKey(-42).String())
+	assert.Equal(t, "-42", NewInt64Key(-42).String())
+	assert.Equal(t, "18446744073709551615", NewUint64Key(1<<64-1).String())
+}
+
+func TestIntKeySpreadsLowBits(t *testing.T) {
+	buckets := make(map[int]int)
+	for i := 0; i < 1024; i++ {
+		buckets[NewIntKey(i<<10).Hash()&15]++
+	}
+	assert.Equal(t, 16, len(buckets))
+}
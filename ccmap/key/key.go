@@ -9,17 +9,35 @@ type Key interface {
 	String() string
 }
 
+// Seeded is implemented by keys that can hash themselves under a seed.
+// Maps draw a random seed, so that keys colliding in their buckets can
+// not be crafted without knowing it.
+type Seeded interface {
+	Key
+	SeededHash(seed uint64) int
+}
+
+// HashOf returns the hash of k under seed. Keys that do not implement
+// Seeded get their Hash mixed with seed, which spreads keys whose hashes
+// only share the low bits but not keys with equal hashes.
+func HashOf(k Key, seed uint64) int {
+	if s, ok := k.(Seeded); ok {
+		return s.SeededHash(seed)
+	}
+	return (int)(hash.Uint64(seed, uint64(k.Hash())))
+}
+
 // stringKey implements Key for a string.
 type stringKey struct {
 	h   int
 	str string
 }
 
-// NewstringKey return a new string key.
+// NewStringKey return a new string key.
 func NewStringKey(str string) Key {
 	return &stringKey{
 		str: str,
-		h:   (int)(hash.BKDRHash(str)),
+		h:   (int)(hash.String(hash.DefaultSeed, str)),
 	}
 }
 
@@ -28,6 +46,11 @@ func (s *stringKey) Hash() int {
 	return s.h
 }
 
+// SeededHash returns the hash code for s under seed.
+func (s *stringKey) SeededHash(seed uint64) int {
+	return (int)(hash.String(seed, s.str))
+}
+
 // Equal checks if s == k.
 func (s *stringKey) Equal(k Key) bool {
 	other, ok := k.(*stringKey)
@@ -3,12 +3,15 @@ package key
 import (
 	"testing"
 
+	"github.com/csimplestring/go-concurrent-map/algo/hash"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestStringKeyHash(t *testing.T) {
 	k := NewStringKey("cat")
-	assert.Equal(t, 211780, k.Hash())
+	assert.Equal(t, int(hash.String(hash.DefaultSeed, "cat")), k.Hash())
+	assert.Equal(t, NewStringKey("cat").Hash(), k.Hash())
+	assert.NotEqual(t, NewStringKey("dog").Hash(), k.Hash())
 }
 
 func TestStringKeyEqual(t *testing.T) {
@@ -25,3 +28,25 @@ func TestStringKeyString(t *testing.T) {
 	k := NewStringKey("s1")
 	assert.Equal(t, "s1", k.String())
 }
+
+func TestStringKeySeededHash(t *testing.T) {
+	k := NewStringKey("cat").(Seeded)
+	assert.Equal(t, int(hash.String(1, "cat")), k.SeededHash(1))
+	assert.NotEqual(t, k.SeededHash(1), k.SeededHash(2))
+}
+
+type plainKey int
+
+func (p plainKey) Hash() int        { return int(p) }
+func (p plainKey) Equal(k Key) bool { return k == Key(p) }
+func (p plainKey) String() string   { return "" }
+
+func TestHashOf(t *testing.T) {
+	k := NewStringKey("cat")
+	assert.Equal(t, k.(Seeded).SeededHash(7), HashOf(k, 7))
+
+	// keys that are not Seeded get their hash mixed with the seed.
+	assert.Equal(t, HashOf(plainKey(1), 7), HashOf(plainKey(1), 7))
+	assert.NotEqual(t, HashOf(plainKey(1), 7), HashOf(plainKey(1), 8))
+	assert.NotEqual(t, HashOf(plainKey(1), 7)&1023, HashOf(plainKey(1025), 7)&1023)
+}
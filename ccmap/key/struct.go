@@ -0,0 +1,56 @@
+package key
+
+import (
+	"fmt"
+	"hash/maphash"
+
+	"github.com/csimplestring/go-concurrent-map/algo/hash"
+)
+
+// structSeed seeds the hash of every Struct key of this process.
+var structSeed = maphash.MakeSeed()
+
+// Struct implements Key for any comparable value, typically a struct or
+// an array used as a tuple like (tenantID, userID, region). Hash, Equal
+// and String are derived from the value, no reflection is involved in
+// Hash and Equal.
+type Struct[T comparable] struct {
+	h int
+	v T
+}
+
+// Of returns a key for v. Keys made from values of different types are
+// never equal. If T is an interface type, v must hold a comparable
+// value, otherwise Of panics.
+func Of[T comparable](v T) Key {
+	return &Struct[T]{
+		v: v,
+		h: (int)(maphash.Comparable(structSeed, v)),
+	}
+}
+
+// Value returns the value s was made of.
+func (s *Struct[T]) Value() T {
+	return s.v
+}
+
+func (s *Struct[T]) Hash() int {
+	return s.h
+}
+
+// SeededHash mixes seed into the hash of s. Since structSeed is random
+// and secret, keys with equal hashes can not be crafted.
+func (s *Struct[T]) SeededHash(seed uint64) int {
+	return (int)(hash.Uint64(seed, uint64(s.h)))
+}
+
+// Equal checks if k holds a value of the same type equal to s.
+func (s *Struct[T]) Equal(k Key) bool {
+	other, ok := k.(*Struct[T])
+	return ok && s.v == other.v
+}
+
+// String formats the value of s with %v.
+func (s *Struct[T]) String() string {
+	return fmt.Sprintf("%v", s.v)
+}
@@ -0,0 +1,55 @@
+package key
+
+import (
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tenantUser struct {
+	TenantID int64
+	UserID   string
+	Region   uint8
+}
+
+type otherTenantUser tenantUser
+
+func TestStructKeyProperties(t *testing.T) {
+	assert.NoError(t, quick.Check(func(a, b tenantUser) bool {
+		return checkConsistent(Of(a), Of(b), a == b) &&
+			checkConsistent(Of(a), Of(a), true)
+	}, nil))
+}
+
+func TestStructKeyFields(t *testing.T) {
+	a := Of(tenantUser{1, "u1", 2})
+
+	assert.True(t, checkConsistent(a, Of(tenantUser{1, "u1", 2}), true))
+	assert.True(t, checkConsistent(a, Of(tenantUser{2, "u1", 2}), false))
+	assert.True(t, checkConsistent(a, Of(tenantUser{1, "u2", 2}), false))
+	assert.True(t, checkConsistent(a, Of(tenantUser{1, "u1", 3}), false))
+	assert.Equal(t, "{1 u1 2}", a.String())
+	assert.Equal(t, tenantUser{1, "u1", 2}, a.(*Struct[tenantUser]).Value())
+}
+
+func TestStructKeyCrossType(t *testing.T) {
+	v := tenantUser{1, "u1", 2}
+	assert.True(t, checkConsistent(Of(v), Of(otherTenantUser(v)), false))
+	assert.True(t, checkConsistent(Of("cat"), NewStringKey("cat"), false))
+}
+
+func TestStructKeyArray(t *testing.T) {
+	assert.NoError(t, quick.Check(func(a, b [3]int32) bool {
+		return checkConsistent(Of(a), Of(b), a == b)
+	}, nil))
+}
+
+func TestStructKeyInterface(t *testing.T) {
+	var a, b any = tenantUser{1, "u1", 2}, tenantUser{1, "u1", 2}
+	assert.True(t, checkConsistent(Of(a), Of(b), true))
+
+	assert.Panics(t, func() {
+		Of[any]([]int{1})
+	})
+}
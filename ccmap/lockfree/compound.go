@@ -0,0 +1,74 @@
+package lockfree
+
+import (
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
+
+// PutIfAbsent puts <key, val> only if key is not present.
+// It returns true if val was stored.
+func (m *splitOrderedMap) PutIfAbsent(key Key, val interface{}) bool {
+	_, _, act := m.update(key, func(_ interface{}, loaded bool) (interface{}, action) {
+		if loaded {
+			return nil, keep
+		}
+		return val, store
+	})
+	return act == store
+}
+
+// Replace replaces the value of key only if key is present.
+// It returns the previous value and true if it was replaced.
+func (m *splitOrderedMap) Replace(key Key, val interface{}) (interface{}, bool) {
+	prev, loaded, _ := m.update(key, func(_ interface{}, loaded bool) (interface{}, action) {
+		if !loaded {
+			return nil, keep
+		}
+		return val, store
+	})
+	return prev, loaded
+}
+
+// CompareAndSwap replaces the value of key with new only if it equals old.
+func (m *splitOrderedMap) CompareAndSwap(key Key, old, new interface{}) bool {
+	_, _, act := m.update(key, func(current interface{}, loaded bool) (interface{}, action) {
+		if !loaded || current != old {
+			return nil, keep
+		}
+		return new, store
+	})
+	return act == store
+}
+
+// CompareAndDelete deletes key only if its value equals old.
+func (m *splitOrderedMap) CompareAndDelete(key Key, old interface{}) bool {
+	_, _, act := m.update(key, func(current interface{}, loaded bool) (interface{}, action) {
+		if !loaded || current != old {
+			return nil, keep
+		}
+		return nil, remove
+	})
+	return act == remove
+}
+
+// LoadOrStore returns the value of key if present, otherwise it stores
+// and returns val.
+func (m *splitOrderedMap) LoadOrStore(key Key, val interface{}) (interface{}, bool) {
+	actual, loaded, _ := m.update(key, func(_ interface{}, loaded bool) (interface{}, action) {
+		if loaded {
+			return nil, keep
+		}
+		return val, store
+	})
+	if !loaded {
+		return val, false
+	}
+	return actual, true
+}
+
+// LoadAndDelete deletes key and returns its previous value.
+func (m *splitOrderedMap) LoadAndDelete(key Key) (interface{}, bool) {
+	prev, loaded, _ := m.update(key, func(interface{}, bool) (interface{}, action) {
+		return nil, remove
+	})
+	return prev, loaded
+}
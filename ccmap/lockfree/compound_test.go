@@ -0,0 +1,51 @@
+package lockfree
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/csimplestring/go-concurrent-map/ccmap/maptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapSuite(t *testing.T) {
+	maptest.Run(t, func() ccmap.Map {
+		m, _ := NewMap(4)
+		return m
+	})
+}
+
+// TestCompoundWhileGrowing runs the compound operations while the number
+// of buckets doubles, new buckets split the lists of the old ones.
+func TestCompoundWhileGrowing(t *testing.T) {
+	m, _ := newMap(1)
+	const writers, n = 8, 500
+
+	var wg sync.WaitGroup
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				k := NewStringKey(fmt.Sprintf("%d-%d", g, i))
+				assert.True(t, m.PutIfAbsent(k, i))
+				assert.True(t, m.CompareAndSwap(k, i, i+1))
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.True(t, m.size.Load() > 1)
+	assert.Equal(t, writers*n, m.Size())
+
+	for g := 0; g < writers; g++ {
+		for i := 0; i < n; i++ {
+			actual, loaded := m.LoadAndDelete(NewStringKey(fmt.Sprintf("%d-%d", g, i)))
+			assert.True(t, loaded)
+			assert.Equal(t, i+1, actual)
+		}
+	}
+	assert.Equal(t, 0, m.Size())
+}
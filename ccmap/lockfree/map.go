@@ -0,0 +1,345 @@
+package lockfree
+
+import (
+	"fmt"
+	"iter"
+	"math/bits"
+	"math/rand/v2"
+	"sync/atomic"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
+
+const (
+	// LOAD_FACTOR is the average number of entries per bucket above which
+	// the number of buckets doubles.
+	LOAD_FACTOR = 2
+)
+
+// levels is the number of levels of the bucket index, level 0 holds
+// bucket 0 and level l holds buckets [2^(l-1), 2^l).
+const levels = 31
+
+// splitOrderedMap is a lock-free ccmap.Map, after "Split-Ordered Lists:
+// Lock-Free Extensible Hash Tables" by Shalev and Shavit.
+//
+// All the entries are kept in a single linked list sorted by the reversed
+// bits of their hash. A bucket is a pointer to a dummy node in that list,
+// in front of the entries whose hash ends with the bits of the bucket.
+// Doubling the number of buckets thus never moves an entry: each new
+// bucket is lazily initialized by inserting its dummy node in the middle
+// of its parent bucket. There is no rehash and no lock, every update is a
+// compare-and-swap of the state of one node.
+//
+// The map never shrinks.
+type splitOrderedMap struct {
+	// index holds the dummy nodes of the buckets, by level. A level is
+	// allocated the first time one of its buckets is used.
+	index [levels]atomic.Pointer[[]atomic.Pointer[node]]
+	// size is the number of buckets, a power of two.
+	size     atomic.Uint64
+	entryCnt atomic.Int64
+	// seed is mixed into the hash of every key.
+	seed uint64
+}
+
+// NewMap creates a lock-free map with at least size buckets, size is
+// rounded up to a power of two.
+func NewMap(size int) (ccmap.Map, error) {
+	return newMap(size)
+}
+
+func newMap(size int) (*splitOrderedMap, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: size %d should be positive", ccmap.ErrInvalidCapacity, size)
+	}
+	if size > ccmap.MAX_TABLE_SIZE {
+		return nil, fmt.Errorf("%w: size %d, max %d", ccmap.ErrCapacityExceeded, size, ccmap.MAX_TABLE_SIZE)
+	}
+
+	n := 1
+	for n < size {
+		n = n << 1
+	}
+
+	m := &splitOrderedMap{
+		seed: rand.Uint64(),
+	}
+	m.size.Store(uint64(n))
+	m.slot(0).Store(newNode(dummyKey(0), nil, nil, nil))
+	return m, nil
+}
+
+// Put puts <key, val> pair in m. It always returns true.
+func (m *splitOrderedMap) Put(key Key, val interface{}) bool {
+	m.update(key, func(interface{}, bool) (interface{}, action) {
+		return val, store
+	})
+	return true
+}
+
+// TryPut puts <key, val> pair in m. It always returns nil.
+func (m *splitOrderedMap) TryPut(key Key, val interface{}) error {
+	m.Put(key, val)
+	return nil
+}
+
+// Get gets the value based on key.
+// If value exists, it returns value and TRUE;
+// otherwise it returns nil and FALSE.
+// Get neither inserts, updates nor unlinks entries, but it may lazily
+// initialize the bucket of key, inserting its dummy node.
+func (m *splitOrderedMap) Get(key Key) (interface{}, bool) {
+	hash := m.hashOf(key)
+	so := regularKey(hash)
+
+	for current := m.bucket(hash).state.Load().next; current != nil; {
+		st := current.state.Load()
+		if current.so > so {
+			break
+		}
+		if !st.deleted && current.matches(so, key) {
+			return st.value, true
+		}
+		current = st.next
+	}
+	return nil, false
+}
+
+// Delete deletes value based on key.
+// It returns TRUE if key exists; otherise FALSE.
+func (m *splitOrderedMap) Delete(key Key) bool {
+	_, ok, _ := m.update(key, func(interface{}, bool) (interface{}, action) {
+		return nil, remove
+	})
+	return ok
+}
+
+// Range calls f for each key and value in m, see ccmap.Map.
+// It walks the list without locking, entries never move so none is
+// visited twice.
+func (m *splitOrderedMap) Range(f func(k Key, val interface{}) bool) {
+	for current := m.slot(0).Load(); current != nil; {
+		st := current.state.Load()
+		if !current.isDummy() && !st.deleted {
+			if !f(current.key, st.value) {
+				return
+			}
+		}
+		current = st.next
+	}
+}
+
+// All returns an iterator over the <key, value> pairs of m.
+func (m *splitOrderedMap) All() iter.Seq2[Key, any] {
+	return m.Range
+}
+
+// Keys returns an iterator over the keys of m.
+func (m *splitOrderedMap) Keys() iter.Seq[Key] {
+	return func(yield func(Key) bool) {
+		m.Range(func(k Key, _ any) bool {
+			return yield(k)
+		})
+	}
+}
+
+// Values returns an iterator over the values of m.
+func (m *splitOrderedMap) Values() iter.Seq[any] {
+	return func(yield func(any) bool) {
+		m.Range(func(_ Key, v any) bool {
+			return yield(v)
+		})
+	}
+}
+
+// Len returns number of entries, see ccmap.Map.
+func (m *splitOrderedMap) Len() int {
+	// a deletion may be counted before the insertion it undoes.
+	if n := m.entryCnt.Load(); n > 0 {
+		return int(n)
+	}
+	return 0
+}
+
+// IsEmpty returns true if m has no entry.
+func (m *splitOrderedMap) IsEmpty() bool {
+	return m.Len() == 0
+}
+
+// Size returns number of entries, it is the same as Len.
+func (m *splitOrderedMap) Size() int {
+	return m.Len()
+}
+
+// hashOf returns the hash of key under the seed of m.
+func (m *splitOrderedMap) hashOf(key Key) uint64 {
+	return uint64(HashOf(key, m.seed))
+}
+
+/*********** Updates ***********/
+
+// action tells update what to do with a key.
+type action int
+
+const (
+	keep action = iota
+	store
+	remove
+)
+
+// update atomically replaces the value of key with the one returned by f,
+// or removes key, depending on the action f returns. f gets the current
+// value and whether key is present. It may be called more than once if
+// key is updated concurrently, so it must have no side effect.
+// update returns the value f was last called with and the action taken,
+// which is keep when remove is asked for a key that is not present.
+func (m *splitOrderedMap) update(key Key, f func(old interface{}, loaded bool) (interface{}, action)) (interface{}, bool, action) {
+	hash := m.hashOf(key)
+	so := regularKey(hash)
+	head := m.bucket(hash)
+
+	for {
+		pred, ps, current, st := m.search(head, so, key)
+		found := current != nil && current.matches(so, key)
+
+		var old interface{}
+		if found {
+			old = st.value
+		}
+
+		val, act := f(old, found)
+		switch {
+		case act == keep, act == remove && !found:
+			return old, found, keep
+
+		case act == store && found:
+			if current.state.CompareAndSwap(st, &state{next: st.next, value: val}) {
+				return old, found, act
+			}
+
+		case act == store:
+			n := newNode(so, key, val, current)
+			if pred.state.CompareAndSwap(ps, &state{next: n, value: ps.value}) {
+				m.entryCnt.Add(1)
+				m.grow()
+				return old, found, act
+			}
+
+		case act == remove:
+			if current.state.CompareAndSwap(st, &state{next: st.next, value: st.value, deleted: true}) {
+				m.entryCnt.Add(-1)
+				// unlink it, if this fails the next search does it.
+				pred.state.CompareAndSwap(ps, &state{next: st.next, value: ps.value})
+				return old, found, act
+			}
+		}
+	}
+}
+
+// search finds in the list starting at head the first node not before so
+// and key: the node of key, or the node before which key must be
+// inserted, nil at the end of the list. It returns it with its state, and
+// the node before it with its state. key is nil to search for a dummy
+// node. Deleted nodes met on the way are unlinked.
+func (m *splitOrderedMap) search(head *node, so uint64, key Key) (*node, *state, *node, *state) {
+retry:
+	pred := head
+	ps := pred.state.Load()
+	for {
+		current := ps.next
+		if current == nil {
+			return pred, ps, nil, nil
+		}
+
+		st := current.state.Load()
+		if st.deleted {
+			unlinked := &state{next: st.next, value: ps.value}
+			if !pred.state.CompareAndSwap(ps, unlinked) {
+				goto retry
+			}
+			ps = unlinked
+			continue
+		}
+
+		if current.so > so || (current.so == so && (key == nil || current.matches(so, key))) {
+			return pred, ps, current, st
+		}
+		pred, ps = current, st
+	}
+}
+
+// grow doubles the number of buckets once there are more than
+// LOAD_FACTOR entries per bucket. It only changes the size, the new
+// buckets are initialized when they are first used.
+func (m *splitOrderedMap) grow() {
+	size := m.size.Load()
+	if uint64(m.entryCnt.Load()) > size*LOAD_FACTOR && size < ccmap.MAX_TABLE_SIZE {
+		m.size.CompareAndSwap(size, size<<1)
+	}
+}
+
+/*********** Buckets ***********/
+
+// bucket returns the dummy node of the bucket of hash, initializing the
+// bucket if needed.
+func (m *splitOrderedMap) bucket(hash uint64) *node {
+	b := hash & (m.size.Load() - 1)
+	if head := m.slot(b).Load(); head != nil {
+		return head
+	}
+	return m.initBucket(b)
+}
+
+// initBucket inserts the dummy node of bucket b in the list, after the
+// dummy node of its parent, and stores it in the index.
+func (m *splitOrderedMap) initBucket(b uint64) *node {
+	// the parent is b without its highest bit, it was split into b.
+	parent := b &^ (1 << (bits.Len64(b) - 1))
+	head := m.slot(parent).Load()
+	if head == nil {
+		head = m.initBucket(parent)
+	}
+
+	so := dummyKey(b)
+	var dummy *node
+	for dummy == nil {
+		pred, ps, current, _ := m.search(head, so, nil)
+		if current != nil && current.so == so {
+			// another goroutine inserted it.
+			dummy = current
+			break
+		}
+
+		n := newNode(so, nil, nil, current)
+		if pred.state.CompareAndSwap(ps, &state{next: n, value: ps.value}) {
+			dummy = n
+		}
+	}
+
+	slot := m.slot(b)
+	slot.CompareAndSwap(nil, dummy)
+	return slot.Load()
+}
+
+// slot returns the index entry of bucket b, allocating its level if needed.
+func (m *splitOrderedMap) slot(b uint64) *atomic.Pointer[node] {
+	level := bits.Len64(b)
+	offset := b
+	if level > 0 {
+		offset = b - 1<<(level-1)
+	}
+
+	slots := m.index[level].Load()
+	if slots == nil {
+		n := 1
+		if level > 0 {
+			n = 1 << (level - 1)
+		}
+		fresh := make([]atomic.Pointer[node], n)
+		m.index[level].CompareAndSwap(nil, &fresh)
+		slots = m.index[level].Load()
+	}
+	return &(*slots)[offset]
+}
@@ -0,0 +1,374 @@
+package lockfree
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/csimplestring/go-concurrent-map/algo/random"
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	benchmarkKeys []Key
+)
+
+func init() {
+	benchmarkKeys = make([]Key, 10000)
+	for i := 0; i < 10000; i++ {
+		benchmarkKeys[i] = NewStringKey(random.NewLen(15))
+	}
+}
+
+func TestNewMap(t *testing.T) {
+	m, err := newMap(6)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(8), m.size.Load())
+
+	_, err = NewMap(0)
+	assert.ErrorIs(t, err, ccmap.ErrInvalidCapacity)
+
+	_, err = NewMap(ccmap.MAX_TABLE_SIZE + 1)
+	assert.ErrorIs(t, err, ccmap.ErrCapacityExceeded)
+}
+
+func TestMapSize(t *testing.T) {
+	m, _ := newMap(6)
+
+	m.Put(NewStringKey("k1"), 1)
+	assert.Equal(t, 1, m.Size())
+
+	m.Put(NewStringKey("k1"), 2)
+	assert.Equal(t, 1, m.Size())
+
+	m.Put(NewStringKey("k2"), 2)
+	assert.Equal(t, 2, m.Size())
+
+	m.Delete(NewStringKey("k1"))
+	assert.Equal(t, 1, m.Size())
+}
+
+func TestMapGetOK(t *testing.T) {
+	m, _ := NewMap(100)
+
+	for i := 0; i < 30; i++ {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		assert.True(t, m.Put(key, i))
+	}
+
+	for i := 0; i < 30; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+	}
+
+	for i := 0; i < 30; i++ {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		m.Put(key, i*2)
+	}
+
+	for i := 0; i < 30; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.True(t, ok)
+		assert.Equal(t, i*2, actual)
+	}
+}
+
+func TestMapGetFail(t *testing.T) {
+	m, _ := NewMap(100)
+
+	for i := 0; i < 30; i++ {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		m.Put(key, i)
+	}
+
+	for i := 31; i < 60; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.False(t, ok)
+		assert.Nil(t, actual)
+	}
+}
+
+func TestMapDeleteOK(t *testing.T) {
+	m, _ := NewMap(100)
+
+	for i := 0; i < 30; i++ {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		m.Put(key, i)
+	}
+
+	for i := 0; i < 30; i++ {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		ok := m.Delete(key)
+		assert.True(t, ok, "%d", i)
+	}
+
+	for i := 0; i < 30; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.False(t, ok)
+		assert.Nil(t, actual)
+	}
+}
+
+func TestMapDeleteFail(t *testing.T) {
+	m, _ := NewMap(100)
+
+	for i := 0; i < 30; i++ {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		m.Put(key, i)
+	}
+
+	for i := 31; i < 60; i++ {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		ok := m.Delete(key)
+		assert.False(t, ok)
+	}
+}
+
+func TestMapGrows(t *testing.T) {
+	m, _ := newMap(1)
+
+	for i := 0; i < 10000; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+	assert.Equal(t, 10000, m.Size())
+	assert.True(t, m.size.Load() >= 10000/LOAD_FACTOR, "size %d", m.size.Load())
+
+	for i := 0; i < 10000; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+	}
+}
+
+// TestMapListOrder checks that the list stays sorted by split-order key
+// and that every bucket points into it.
+func TestMapListOrder(t *testing.T) {
+	m, _ := newMap(1)
+	for i := 0; i < 1000; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+	for i := 0; i < 1000; i += 2 {
+		m.Delete(NewStringKey(fmt.Sprintf("%d", i)))
+	}
+
+	var prev uint64
+	dummies, regulars := 0, 0
+	for current := m.slot(0).Load(); current != nil; current = current.state.Load().next {
+		assert.True(t, current.so >= prev)
+		prev = current.so
+		if current.isDummy() {
+			dummies++
+			continue
+		}
+		regulars++
+		hash := m.hashOf(current.key)
+		assert.True(t, m.bucket(hash).so <= current.so)
+	}
+	assert.Equal(t, 500, regulars)
+	assert.True(t, dummies <= int(m.size.Load()))
+}
+
+func TestMapCollidingKeys(t *testing.T) {
+	m, _ := newMap(4)
+
+	// keys with the same hash share their split-order key.
+	k1, k2 := NewIntKey(7), NewInt64Key(7)
+	assert.Equal(t, m.hashOf(k1), m.hashOf(k2))
+
+	m.Put(k1, 1)
+	m.Put(k2, 2)
+	assert.Equal(t, 2, m.Size())
+
+	actual, _ := m.Get(k1)
+	assert.Equal(t, 1, actual)
+	actual, _ = m.Get(k2)
+	assert.Equal(t, 2, actual)
+
+	assert.True(t, m.Delete(k1))
+	_, ok := m.Get(k1)
+	assert.False(t, ok)
+	actual, _ = m.Get(k2)
+	assert.Equal(t, 2, actual)
+}
+
+// TestMapConcurrent runs readers and writers in parallel on a map that
+// keeps growing, run it with -race.
+func TestMapConcurrent(t *testing.T) {
+	m, _ := newMap(1)
+	for i := 0; i < 100; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("stable-%d", i)), i)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := NewStringKey(fmt.Sprintf("w%d-%d", g, i))
+				m.Put(key, i)
+				if i%3 == 0 {
+					m.Delete(key)
+				}
+			}
+		}(g)
+	}
+
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := NewStringKey(fmt.Sprintf("stable-%d", i%100))
+				if actual, ok := m.Get(key); !ok || actual != i%100 {
+					t.Errorf("get %s: %v %v", key, actual, ok)
+					return
+				}
+				m.Get(NewStringKey(fmt.Sprintf("w%d-%d", g, i)))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	expected := 100
+	for g := 0; g < 8; g++ {
+		for i := 0; i < 2000; i++ {
+			actual, ok := m.Get(NewStringKey(fmt.Sprintf("w%d-%d", g, i)))
+			if i%3 == 0 {
+				assert.False(t, ok)
+				continue
+			}
+			expected++
+			assert.True(t, ok)
+			assert.Equal(t, i, actual)
+		}
+	}
+	assert.Equal(t, expected, m.Size())
+}
+
+func TestMapRange(t *testing.T) {
+	m, _ := NewMap(16)
+
+	for i := 0; i < 100; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+
+	visited := make(map[string]interface{})
+	m.Range(func(k Key, v interface{}) bool {
+		_, dup := visited[k.String()]
+		assert.False(t, dup, "key %s", k)
+		visited[k.String()] = v
+		return true
+	})
+
+	assert.Equal(t, 100, len(visited))
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, i, visited[fmt.Sprintf("%d", i)])
+	}
+}
+
+func TestMapRangeStop(t *testing.T) {
+	m, _ := NewMap(16)
+
+	for i := 0; i < 100; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+
+	cnt := 0
+	m.Range(func(k Key, v interface{}) bool {
+		cnt++
+		return cnt < 10
+	})
+	assert.Equal(t, 10, cnt)
+}
+
+// TestMapRangeMutate checks that every entry present for the whole
+// Range is visited exactly once while other entries come and go.
+func TestMapRangeMutate(t *testing.T) {
+	m, _ := newMap(1)
+	for i := 0; i < 1000; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("stable-%d", i)), i)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			key := NewStringKey(fmt.Sprintf("tmp-%d", i))
+			m.Put(key, i)
+			if i%2 == 0 {
+				m.Delete(key)
+			}
+		}
+	}()
+
+	for r := 0; r < 10; r++ {
+		visited := make(map[string]bool)
+		m.Range(func(k Key, v interface{}) bool {
+			assert.False(t, visited[k.String()], "key %s", k)
+			visited[k.String()] = true
+			// f may call the map.
+			m.Get(k)
+			return true
+		})
+
+		stable := 0
+		for k := range visited {
+			if len(k) > 7 && k[:7] == "stable-" {
+				stable++
+			}
+		}
+		assert.Equal(t, 1000, stable)
+	}
+
+	close(done)
+	wg.Wait()
+}
+
+func BenchmarkMapPut(b *testing.B) {
+	m, _ := NewMap(100)
+
+	for i, k := range benchmarkKeys {
+		m.Put(k, i)
+	}
+}
+
+func BenchmarkMapGet(b *testing.B) {
+	m, _ := NewMap(100)
+
+	size := len(benchmarkKeys)
+	for i := 0; i < size/2; i++ {
+		m.Put(benchmarkKeys[i], i)
+	}
+	b.StopTimer()
+	b.StartTimer()
+
+	for _, k := range benchmarkKeys {
+		m.Get(k)
+	}
+}
+
+func BenchmarkMapDelete(b *testing.B) {
+	m, _ := NewMap(100)
+
+	size := len(benchmarkKeys)
+	for i := 0; i < size/2; i++ {
+		m.Put(benchmarkKeys[i], i)
+	}
+	b.StopTimer()
+	b.StartTimer()
+
+	for _, k := range benchmarkKeys {
+		m.Delete(k)
+	}
+}
@@ -0,0 +1,62 @@
+package lockfree
+
+import (
+	"math/bits"
+	"sync/atomic"
+
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
+
+// node is an element of the split-ordered list. It is either a dummy node,
+// the entry point of a bucket, or a regular node holding an entry.
+//
+// The fields of a node never change, everything that does is kept in an
+// immutable state that is replaced with compare-and-swap. Swapping the
+// whole state updates the link, the value and the deletion mark at once,
+// so a node cannot be linked to after it was deleted, nor its value be
+// lost by a concurrent insertion after it.
+type node struct {
+	// so is the split-order key, see regularKey and dummyKey.
+	so    uint64
+	key   Key
+	state atomic.Pointer[state]
+}
+
+// state is the mutable part of a node, it is never modified once stored.
+type state struct {
+	next    *node
+	value   interface{}
+	deleted bool
+}
+
+// newNode creates a node linked to next.
+func newNode(so uint64, key Key, val interface{}, next *node) *node {
+	n := &node{
+		so:  so,
+		key: key,
+	}
+	n.state.Store(&state{next: next, value: val})
+	return n
+}
+
+// isDummy returns true if n is the dummy node of a bucket.
+func (n *node) isDummy() bool {
+	return n.so&1 == 0
+}
+
+// matches returns true if n is the regular node of key.
+func (n *node) matches(so uint64, key Key) bool {
+	return n.so == so && !n.isDummy() && n.key.Equal(key)
+}
+
+// regularKey returns the split-order key of a regular node: the reversed
+// bits of hash with the lowest bit set, which sorts it after the dummy
+// node of its bucket.
+func regularKey(hash uint64) uint64 {
+	return bits.Reverse64(hash) | 1
+}
+
+// dummyKey returns the split-order key of the dummy node of bucket.
+func dummyKey(bucket uint64) uint64 {
+	return bits.Reverse64(bucket) &^ 1
+}
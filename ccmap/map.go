@@ -1,18 +1,76 @@
 package ccmap
 
-import "github.com/csimplestring/go-concurrent-map/ccmap/key"
+import (
+	"iter"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
 
 const (
 	BUCKET_SIZE_DEFAULT = 16
-	StatusRehashing     = -1
+	// MAX_TABLE_SIZE is the largest number of buckets of a table, tables
+	// stop growing there instead of overflowing int.
+	MAX_TABLE_SIZE  = 1 << 30
+	StatusRehashing = -1
 )
 
 // Map defines the functions that a map should support
-// TODO:
-// 2. shrink table
-// 3. rehash table in background
 type Map interface {
+	// Put stores val for k. It returns false if val could not be stored,
+	// which happens when k is new and the map cannot grow any more
+	// because it reached MAX_TABLE_SIZE. The map is left unchanged in
+	// that case.
 	Put(k key.Key, val interface{}) bool
+	// TryPut stores val for k like Put, but it returns an error wrapping
+	// ErrCapacityExceeded if val could not be stored.
+	TryPut(k key.Key, val interface{}) error
 	Get(k key.Key) (interface{}, bool)
 	Delete(k key.Key) bool
+
+	// Len returns the number of entries without blocking. While the map
+	// is modified concurrently it may miss the latest writes, once they
+	// are all done it is exact.
+	Len() int
+	// IsEmpty returns true if Len() == 0.
+	IsEmpty() bool
+
+	// The following operations are atomic: no other write to k can
+	// happen between checking the current value and updating it.
+	// Values are compared with ==, comparing values that are not
+	// comparable panics.
+
+	// PutIfAbsent puts val only if k is not present.
+	// It returns true if val was stored.
+	PutIfAbsent(k key.Key, val interface{}) bool
+	// Replace replaces the value of k only if k is present.
+	// It returns the previous value and true if it was replaced.
+	Replace(k key.Key, val interface{}) (interface{}, bool)
+	// CompareAndSwap replaces the value of k with new only if it
+	// equals old. It returns true if the value was swapped.
+	CompareAndSwap(k key.Key, old, new interface{}) bool
+	// CompareAndDelete deletes k only if its value equals old.
+	// It returns true if k was deleted.
+	CompareAndDelete(k key.Key, old interface{}) bool
+	// LoadOrStore returns the value of k if present. Otherwise it
+	// stores and returns val. loaded is true if the value was present.
+	// If val cannot be stored, see Put, it returns nil and false.
+	LoadOrStore(k key.Key, val interface{}) (actual interface{}, loaded bool)
+	// LoadAndDelete deletes k and returns its previous value.
+	// loaded is true if k was present.
+	LoadAndDelete(k key.Key) (val interface{}, loaded bool)
+
+	// Range calls f sequentially for each key and value present in the
+	// map. If f returns false, Range stops the iteration.
+	//
+	// Range is weakly consistent: it never panics and never blocks
+	// writers while f runs, every entry present for the whole call is
+	// visited exactly once, entries added or removed concurrently may or
+	// may not be visited. f may safely call any method of the map.
+	Range(f func(k key.Key, val interface{}) bool)
+
+	// All, Keys and Values return iterators over the map with the same
+	// consistency as Range.
+	All() iter.Seq2[key.Key, any]
+	Keys() iter.Seq[key.Key]
+	Values() iter.Seq[any]
 }
@@ -0,0 +1,167 @@
+package maptest
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPutIfAbsent(t *testing.T, m ccmap.Map) {
+	k := NewStringKey("k1")
+
+	assert.True(t, m.PutIfAbsent(k, 1))
+	assert.False(t, m.PutIfAbsent(k, 2))
+
+	actual, ok := m.Get(k)
+	assert.True(t, ok)
+	assert.Equal(t, 1, actual)
+}
+
+func testReplace(t *testing.T, m ccmap.Map) {
+	k := NewStringKey("k1")
+
+	prev, ok := m.Replace(k, 1)
+	assert.False(t, ok)
+	assert.Nil(t, prev)
+	_, ok = m.Get(k)
+	assert.False(t, ok)
+
+	m.Put(k, 1)
+	prev, ok = m.Replace(k, 2)
+	assert.True(t, ok)
+	assert.Equal(t, 1, prev)
+
+	actual, _ := m.Get(k)
+	assert.Equal(t, 2, actual)
+}
+
+func testCompareAndSwap(t *testing.T, m ccmap.Map) {
+	k := NewStringKey("k1")
+
+	assert.False(t, m.CompareAndSwap(k, nil, 1))
+	_, ok := m.Get(k)
+	assert.False(t, ok)
+
+	m.Put(k, 1)
+	assert.False(t, m.CompareAndSwap(k, 2, 3))
+	assert.True(t, m.CompareAndSwap(k, 1, 3))
+
+	actual, _ := m.Get(k)
+	assert.Equal(t, 3, actual)
+}
+
+func testCompareAndDelete(t *testing.T, m ccmap.Map) {
+	k := NewStringKey("k1")
+
+	assert.False(t, m.CompareAndDelete(k, 1))
+
+	m.Put(k, 1)
+	assert.False(t, m.CompareAndDelete(k, 2))
+	_, ok := m.Get(k)
+	assert.True(t, ok)
+
+	assert.True(t, m.CompareAndDelete(k, 1))
+	_, ok = m.Get(k)
+	assert.False(t, ok)
+}
+
+func testLoadOrStore(t *testing.T, m ccmap.Map) {
+	k := NewStringKey("k1")
+
+	actual, loaded := m.LoadOrStore(k, 1)
+	assert.False(t, loaded)
+	assert.Equal(t, 1, actual)
+
+	actual, loaded = m.LoadOrStore(k, 2)
+	assert.True(t, loaded)
+	assert.Equal(t, 1, actual)
+}
+
+func testLoadAndDelete(t *testing.T, m ccmap.Map) {
+	k := NewStringKey("k1")
+
+	actual, loaded := m.LoadAndDelete(k)
+	assert.False(t, loaded)
+	assert.Nil(t, actual)
+
+	m.Put(k, 1)
+	actual, loaded = m.LoadAndDelete(k)
+	assert.True(t, loaded)
+	assert.Equal(t, 1, actual)
+
+	_, ok := m.Get(k)
+	assert.False(t, ok)
+}
+
+func testPutIfAbsentConcurrent(t *testing.T, m ccmap.Map) {
+	var stored int64
+	var wg sync.WaitGroup
+
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if m.PutIfAbsent(NewStringKey(fmt.Sprintf("%d", i)), g) {
+					atomic.AddInt64(&stored, 1)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(100), stored)
+}
+
+func testCompareAndSwapConcurrent(t *testing.T, m ccmap.Map) {
+	k := NewStringKey("counter")
+	m.Put(k, 0)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				for {
+					old, _ := m.Get(k)
+					if m.CompareAndSwap(k, old, old.(int)+1) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	actual, _ := m.Get(k)
+	assert.Equal(t, 1600, actual)
+}
+
+func testLoadAndDeleteConcurrent(t *testing.T, m ccmap.Map) {
+	for i := 0; i < 100; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+
+	var loaded int64
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if _, ok := m.LoadAndDelete(NewStringKey(fmt.Sprintf("%d", i))); ok {
+					atomic.AddInt64(&loaded, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(100), loaded)
+}
@@ -0,0 +1,85 @@
+package maptest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+// fill puts the keys "0" to "n-1" in m, mapped to their index.
+func fill(m ccmap.Map, n int) {
+	for i := 0; i < n; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+}
+
+func testAll(t *testing.T, m ccmap.Map) {
+	fill(m, 500)
+
+	visited := make(map[string]any)
+	for k, v := range m.All() {
+		_, dup := visited[k.String()]
+		assert.False(t, dup, "key %s", k)
+		visited[k.String()] = v
+	}
+
+	assert.Equal(t, 500, len(visited))
+	for i := 0; i < 500; i++ {
+		assert.Equal(t, i, visited[fmt.Sprintf("%d", i)])
+	}
+}
+
+func testKeys(t *testing.T, m ccmap.Map) {
+	fill(m, 500)
+
+	visited := make(map[string]bool)
+	for k := range m.Keys() {
+		assert.False(t, visited[k.String()], "key %s", k)
+		visited[k.String()] = true
+	}
+	assert.Equal(t, 500, len(visited))
+}
+
+func testValues(t *testing.T, m ccmap.Map) {
+	fill(m, 500)
+
+	sum := 0
+	for v := range m.Values() {
+		sum += v.(int)
+	}
+	assert.Equal(t, 499*500/2, sum)
+}
+
+func testIterBreak(t *testing.T, m ccmap.Map) {
+	fill(m, 500)
+
+	cnt := 0
+	for range m.All() {
+		cnt++
+		if cnt == 10 {
+			break
+		}
+	}
+	assert.Equal(t, 10, cnt)
+
+	cnt = 0
+	for range m.Keys() {
+		cnt++
+		if cnt == 5 {
+			break
+		}
+	}
+	assert.Equal(t, 5, cnt)
+
+	cnt = 0
+	for range m.Values() {
+		cnt++
+		if cnt == 7 {
+			break
+		}
+	}
+	assert.Equal(t, 7, cnt)
+}
@@ -0,0 +1,82 @@
+package maptest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+func testLen(t *testing.T, m ccmap.Map) {
+	assert.True(t, m.IsEmpty())
+	assert.Equal(t, 0, m.Len())
+
+	for i := 0; i < 3; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+	m.Put(NewStringKey("0"), 10)
+	assert.False(t, m.IsEmpty())
+	assert.Equal(t, 3, m.Len())
+
+	m.Delete(NewStringKey("1"))
+	m.Delete(NewStringKey("absent"))
+	assert.Equal(t, 2, m.Len())
+
+	m.Delete(NewStringKey("0"))
+	m.Delete(NewStringKey("2"))
+	assert.True(t, m.IsEmpty())
+}
+
+// testLenConcurrent checks that Len stays in bounds while entries are put
+// and deleted concurrently, and is exact once they are done.
+func testLenConcurrent(t *testing.T, m ccmap.Map) {
+	const writers, n = 8, 1000
+
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if l := m.Len(); l < 0 || l > writers*n {
+				t.Errorf("len %d out of bounds", l)
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				key := NewStringKey(fmt.Sprintf("%d-%d", g, i))
+				m.Put(key, i)
+				if i%3 == 0 {
+					m.Delete(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(done)
+	<-sampled
+
+	assert.Equal(t, writers*(n-(n+2)/3), m.Len())
+	assert.False(t, m.IsEmpty())
+
+	cnt := 0
+	m.Range(func(Key, interface{}) bool {
+		cnt++
+		return true
+	})
+	assert.Equal(t, cnt, m.Len())
+}
@@ -0,0 +1,41 @@
+// Package maptest is the test suite shared by the ccmap.Map
+// implementations.
+package maptest
+
+import (
+	"testing"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+)
+
+// tests are the tests of the suite, each one runs on an empty map.
+var tests = []struct {
+	name string
+	f    func(t *testing.T, m ccmap.Map)
+}{
+	{"PutIfAbsent", testPutIfAbsent},
+	{"Replace", testReplace},
+	{"CompareAndSwap", testCompareAndSwap},
+	{"CompareAndDelete", testCompareAndDelete},
+	{"LoadOrStore", testLoadOrStore},
+	{"LoadAndDelete", testLoadAndDelete},
+	{"PutIfAbsentConcurrent", testPutIfAbsentConcurrent},
+	{"CompareAndSwapConcurrent", testCompareAndSwapConcurrent},
+	{"LoadAndDeleteConcurrent", testLoadAndDeleteConcurrent},
+	{"All", testAll},
+	{"Keys", testKeys},
+	{"Values", testValues},
+	{"IterBreak", testIterBreak},
+	{"Len", testLen},
+	{"LenConcurrent", testLenConcurrent},
+}
+
+// Run runs the suite as subtests of t, giving each test a new empty map
+// returned by newMap.
+func Run(t *testing.T, newMap func() ccmap.Map) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.f(t, newMap())
+		})
+	}
+}
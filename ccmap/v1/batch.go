@@ -0,0 +1,86 @@
+package v1
+
+import (
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
+
+// PutAll puts every entry of entries, see Put. Entries are grouped by
+// segment and each segment is locked once, so writing many keys costs
+// one lock acquisition per segment instead of one per key. If a key is
+// given twice the last entry wins. ok[i] reports whether entries[i] was
+// stored.
+func (c *concurrentHashMap) PutAll(entries []Entry) []bool {
+	set := c.load()
+	ok := make([]bool, len(entries))
+	groups := set.group(len(entries), func(i int) Key {
+		return entries[i].Key()
+	})
+	for seg, idx := range groups {
+		if len(idx) > 0 {
+			set.segments[seg].putAll(entries, idx, ok)
+		}
+	}
+	return ok
+}
+
+// GetMany gets the values of keys, see Get. Like Get it takes no lock.
+// vals[i] and ok[i] are the result of keys[i].
+func (c *concurrentHashMap) GetMany(keys []Key) ([]interface{}, []bool) {
+	set := c.load()
+	vals := make([]interface{}, len(keys))
+	ok := make([]bool, len(keys))
+	for i, k := range keys {
+		vals[i], ok[i] = set.segmentOf(k).Get(k)
+	}
+	return vals, ok
+}
+
+// DeleteMany deletes keys, see Delete. Like PutAll it locks each segment
+// once. ok[i] reports whether keys[i] was present.
+func (c *concurrentHashMap) DeleteMany(keys []Key) []bool {
+	set := c.load()
+	ok := make([]bool, len(keys))
+	groups := set.group(len(keys), func(i int) Key {
+		return keys[i]
+	})
+	for seg, idx := range groups {
+		if len(idx) > 0 {
+			set.segments[seg].deleteMany(keys, idx, ok)
+		}
+	}
+	return ok
+}
+
+// group returns the indexes of the n keys given by key, grouped by the
+// segment of the key, in their original order.
+func (set *segmentSet) group(n int, key func(i int) Key) [][]int {
+	groups := make([][]int, len(set.segments))
+	for i := 0; i < n; i++ {
+		k := key(i)
+		seg := set.segmentFor(set.hash(set.hasher(k, set.seed)))
+		groups[seg] = append(groups[seg], i)
+	}
+	return groups
+}
+
+// putAll puts entries[i] for every i of idx under a single write lock
+// and sets ok[i] to the result.
+func (h *hashMap) putAll(entries []Entry, idx []int, ok []bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, i := range idx {
+		ok[i] = h.put(entries[i].Key(), entries[i].Value())
+	}
+}
+
+// deleteMany deletes keys[i] for every i of idx under a single write lock
+// and sets ok[i] to the result.
+func (h *hashMap) deleteMany(keys []Key, idx []int, ok []bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, i := range idx {
+		_, ok[i] = h.remove(keys[i])
+	}
+}
@@ -0,0 +1,173 @@
+package v1
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutAll(t *testing.T) {
+	c, _ := New(WithSegments(4))
+
+	entries := make([]Entry, 0, 101)
+	for i := 0; i < 100; i++ {
+		entries = append(entries, NewEntry(NewStringKey(fmt.Sprintf("%d", i)), i))
+	}
+	// the last entry of a key wins.
+	entries = append(entries, NewEntry(NewStringKey("0"), 1000))
+
+	ok := c.PutAll(entries)
+	assert.Len(t, ok, 101)
+	for i := range ok {
+		assert.True(t, ok[i])
+	}
+	assert.Equal(t, 100, c.Len())
+
+	actual, found := c.Get(NewStringKey("0"))
+	assert.True(t, found)
+	assert.Equal(t, 1000, actual)
+	actual, found = c.Get(NewStringKey("99"))
+	assert.True(t, found)
+	assert.Equal(t, 99, actual)
+
+	assert.Empty(t, c.PutAll(nil))
+}
+
+func TestGetMany(t *testing.T) {
+	c, _ := New(WithSegments(4))
+	c.Put(NewStringKey("k1"), 1)
+	c.Put(NewStringKey("k3"), 3)
+
+	vals, ok := c.GetMany([]Key{NewStringKey("k1"), NewStringKey("k2"), NewStringKey("k3")})
+	assert.Equal(t, []interface{}{1, nil, 3}, vals)
+	assert.Equal(t, []bool{true, false, true}, ok)
+}
+
+func TestDeleteMany(t *testing.T) {
+	c, _ := New(WithSegments(4))
+	for i := 0; i < 100; i++ {
+		c.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+
+	keys := []Key{NewStringKey("1"), NewStringKey("absent"), NewStringKey("2"), NewStringKey("1")}
+	assert.Equal(t, []bool{true, false, true, false}, c.DeleteMany(keys))
+	assert.Equal(t, 98, c.Len())
+
+	_, found := c.Get(NewStringKey("1"))
+	assert.False(t, found)
+}
+
+// TestBatchConcurrent runs batches and single calls in parallel, run it
+// with -race.
+func TestBatchConcurrent(t *testing.T) {
+	c, _ := New(WithSegments(4))
+
+	const writers, n = 4, 500
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			entries := make([]Entry, n)
+			keys := make([]Key, n)
+			for i := range entries {
+				keys[i] = NewStringKey(fmt.Sprintf("%d-%d", w, i))
+				entries[i] = NewEntry(keys[i], i)
+			}
+
+			c.PutAll(entries)
+			vals, ok := c.GetMany(keys)
+			for i := range keys {
+				assert.True(t, ok[i])
+				assert.Equal(t, i, vals[i])
+			}
+			c.Put(NewStringKey(fmt.Sprintf("single-%d", w)), w)
+			c.DeleteMany(keys[:n/2])
+		}(w)
+	}
+	wg.Wait()
+
+	assert.Equal(t, writers*(n/2+1), c.Len())
+}
+
+// benchmarkEntries are the entries of benchmarkKeys.
+func benchmarkEntries() []Entry {
+	entries := make([]Entry, len(benchmarkKeys))
+	for i, k := range benchmarkKeys {
+		entries[i] = NewEntry(k, i)
+	}
+	return entries
+}
+
+func BenchmarkCCHashMapPutAll(b *testing.B) {
+	entries := benchmarkEntries()
+	c, _ := NewConcurrentMap(16)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.PutAll(entries)
+	}
+}
+
+func BenchmarkCCHashMapPutLoop(b *testing.B) {
+	entries := benchmarkEntries()
+	m, _ := NewConcurrentMap(16)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, en := range entries {
+			m.Put(en.Key(), en.Value())
+		}
+	}
+}
+
+func BenchmarkCCHashMapGetMany(b *testing.B) {
+	c, _ := NewConcurrentMap(16)
+	c.PutAll(benchmarkEntries())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.GetMany(benchmarkKeys)
+	}
+}
+
+func BenchmarkCCHashMapGetLoop(b *testing.B) {
+	m, _ := NewConcurrentMap(16)
+	m.PutAll(benchmarkEntries())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, k := range benchmarkKeys {
+			m.Get(k)
+		}
+	}
+}
+
+func BenchmarkCCHashMapDeleteMany(b *testing.B) {
+	entries := benchmarkEntries()
+	c, _ := NewConcurrentMap(16)
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		c.PutAll(entries)
+		b.StartTimer()
+		c.DeleteMany(benchmarkKeys)
+	}
+}
+
+func BenchmarkCCHashMapDeleteLoop(b *testing.B) {
+	entries := benchmarkEntries()
+	c, _ := NewConcurrentMap(16)
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		c.PutAll(entries)
+		b.StartTimer()
+		for _, k := range benchmarkKeys {
+			c.Delete(k)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package v1
 
-import . "github.com/csimplestring/go-concurrent-map/ccmap/key"
+import (
+	"sync/atomic"
+
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
 
 const (
 	entryErr     = 0
@@ -24,50 +28,52 @@ type Bucket interface {
 // newBucket creates a new bucket.
 func newBucket() Bucket {
 	return &bucket{
-		cnt:  0,
-		head: newLinkedEntry(nil, nil),
+		cnt: 0,
 	}
 }
 
 // bucket is the default Bucket implementation.
+//
+// Its linkedEntry nodes are never modified once linked: writers build a
+// new chain, sharing the unchanged tail, and publish it by storing head.
+// So Get can run without a lock, concurrently with one writer. Writers
+// must be serialized by the caller.
 type bucket struct {
 	cnt  int
-	head *linkedEntry
+	head atomic.Pointer[linkedEntry]
 }
 
-// Push appends en at the end of b.
+// Push appends en at the end of b. It copies the whole chain.
 func (b *bucket) Push(en Entry) bool {
-	tail := b.head
-	for current := b.head.next; current != nil; current = current.next {
-		tail = current
-	}
-	tail.next = newLinkedEntry(en, nil)
+	b.head.Store(b.copyUntil(nil, newLinkedEntry(en, nil), nil))
 	b.cnt++
 	return true
 }
 
-// Put appends en at the beginning of b.
+// Put appends en at the beginning of b, or replaces the entry of the same
+// key in place.
 func (b *bucket) Put(en Entry) int {
 	var p *linkedEntry = nil
-	for current := b.head.next; current != nil; current = current.next {
+	for current := b.head.Load(); current != nil; current = current.next {
 		if current.Key().Equal(en.Key()) {
 			p = current
 		}
 	}
 
 	if p == nil {
-		b.head.next = newLinkedEntry(en, b.head.next)
+		b.head.Store(newLinkedEntry(en, b.head.Load()))
 		b.cnt++
 		return entryAdd
 	} else {
-		p.SetValue(en.Value())
+		b.head.Store(b.copyUntil(p, newLinkedEntry(en, p.next), nil))
 		return entryReplace
 	}
 }
 
-// Get finds entry based on key.
+// Get finds entry based on key. It is safe to call it concurrently
+// with a writer.
 func (b *bucket) Get(key Key) (Entry, bool) {
-	for current := b.head.next; current != nil; current = current.next {
+	for current := b.head.Load(); current != nil; current = current.next {
 		if current.Key().Equal(key) {
 			return current.Entry, true
 		}
@@ -81,16 +87,20 @@ func (b *bucket) Delete(key Key) (Entry, int) {
 	var i = 0
 	var d Entry
 
-	prev := b.head
-	for current := b.head.next; current != nil; current = current.next {
+	// last is the last node to delete, the chain after it is kept.
+	var last *linkedEntry
+	for current := b.head.Load(); current != nil; current = current.next {
 		if current.Key().Equal(key) {
-			prev.next = current.next
 			if i == 0 {
 				d = current.Entry
 			}
+			last = current
 			i++
 		}
-		prev = current
+	}
+
+	if i > 0 {
+		b.head.Store(b.copyUntil(last, last.next, key))
 	}
 
 	b.cnt = b.cnt - i
@@ -99,19 +109,44 @@ func (b *bucket) Delete(key Key) (Entry, int) {
 
 // Pop pops the first entry. Returns false if no entry in b.
 func (b *bucket) Pop() (Entry, bool) {
-	if first := b.head.next; first != nil {
-		b.head.next = first.next
+	if first := b.head.Load(); first != nil {
+		b.head.Store(first.next)
 		b.cnt--
 		return first.Entry, true
 	}
 	return nil, false
 }
 
+// copyUntil copies the nodes of b before stop, except those of drop if
+// it is not nil, and links the copy to rest. It returns the head of the
+// new chain, which is not published yet.
+func (b *bucket) copyUntil(stop, rest *linkedEntry, drop Key) *linkedEntry {
+	var head, tail *linkedEntry
+	for current := b.head.Load(); current != stop; current = current.next {
+		if drop != nil && current.Key().Equal(drop) {
+			continue
+		}
+		node := newLinkedEntry(current.Entry, nil)
+		if tail == nil {
+			head = node
+		} else {
+			tail.next = node
+		}
+		tail = node
+	}
+
+	if tail == nil {
+		return rest
+	}
+	tail.next = rest
+	return head
+}
+
 // Entries returns a slice of all the Entries in b.
 func (b *bucket) Entries() []Entry {
 	entries := make([]Entry, b.cnt)
 	i := 0
-	for current := b.head.next; current != nil; current = current.next {
+	for current := b.head.Load(); current != nil; current = current.next {
 		entries[i] = current.Entry
 		i++
 	}
@@ -127,7 +162,7 @@ func (b *bucket) Size() int {
 // String returns a string representation of b.
 func (b *bucket) String() string {
 	str := "["
-	current := b.head.next
+	current := b.head.Load()
 	for current != nil {
 		str += current.Entry.String() + ","
 		current = current.next
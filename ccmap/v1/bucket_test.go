@@ -23,6 +23,32 @@ func TestBucketPush(t *testing.T) {
 	assert.Equal(t, "[[k1 1],[k2 2],[k3 3],]", b.String())
 }
 
+func TestBucketCopyOnWrite(t *testing.T) {
+	b := newBucket().(*bucket)
+	b.Put(newEntry(NewStringKey("k1"), 1))
+	b.Put(newEntry(NewStringKey("k2"), 2))
+	b.Put(newEntry(NewStringKey("k3"), 3))
+
+	// a reader that loaded the head keeps seeing the same chain.
+	head := b.head.Load()
+	chain := func() string {
+		str := ""
+		for current := head; current != nil; current = current.next {
+			str += current.String()
+		}
+		return str
+	}
+
+	b.Put(newEntry(NewStringKey("k2"), 7))
+	b.Delete(NewStringKey("k1"))
+	b.Push(newEntry(NewStringKey("k4"), 4))
+	b.Pop()
+
+	assert.Equal(t, "[k3 3][k2 2][k1 1]", chain())
+	assert.Equal(t, "[[k2 7],[k4 4],]", b.String())
+	assert.Equal(t, 2, b.Size())
+}
+
 func TestBucketPut(t *testing.T) {
 	tests := []struct {
 		b   Bucket
@@ -0,0 +1,171 @@
+package v1
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashMapClear(t *testing.T) {
+	m := newRehashingMap(t)
+	minSize := m.minSize
+
+	m.Clear()
+	assert.False(t, m.isRehashing())
+	assert.Nil(t, m.tables[1])
+	assert.Equal(t, minSize, m.tables[0].len())
+	assert.True(t, m.IsEmpty())
+	assert.Equal(t, 0, m.Size())
+
+	_, ok := m.Get(NewStringKey("r0"))
+	assert.False(t, ok)
+
+	fill(m, 10)
+	assert.Equal(t, 10, m.Len())
+}
+
+func TestHashMapClearKeepsTables(t *testing.T) {
+	m := newRehashingMap(t)
+	minSize := m.minSize
+	next := m.tables[1]
+	m.newTable = func(size int) (table, error) {
+		return nil, fmt.Errorf("%w: no table", ccmap.ErrCapacityExceeded)
+	}
+
+	m.Clear()
+	assert.False(t, m.isRehashing())
+	assert.True(t, next == m.tables[0])
+	assert.True(t, m.tables[0].len() > minSize)
+	assert.Equal(t, 0, m.tables[0].size())
+	assert.True(t, m.IsEmpty())
+
+	_, ok := m.Get(NewStringKey("r0"))
+	assert.False(t, ok)
+	fill(m, 5)
+	assert.Equal(t, 5, m.Len())
+}
+
+func TestCCHashMapClear(t *testing.T) {
+	m, _ := New(WithSegments(4))
+	for i := 0; i < 1000; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+
+	m.Clear()
+	assert.True(t, m.IsEmpty())
+	for _, s := range m.(*concurrentHashMap).load().segments {
+		assert.Equal(t, ccmap.BUCKET_SIZE_DEFAULT, s.tables[0].len())
+		assert.False(t, s.isRehashing())
+	}
+
+	m.Put(NewStringKey("k1"), 1)
+	actual, ok := m.Get(NewStringKey("k1"))
+	assert.True(t, ok)
+	assert.Equal(t, 1, actual)
+}
+
+func TestReplaceAll(t *testing.T) {
+	c, _ := New()
+	src, _ := New()
+	for i := 0; i < 10; i++ {
+		c.Put(NewStringKey(fmt.Sprintf("%d", i)), "old")
+		src.Put(NewStringKey(fmt.Sprintf("%d", i+5)), "new")
+	}
+
+	assert.NoError(t, c.ReplaceAll(src))
+	assert.Equal(t, 10, c.Len())
+	for i := 0; i < 5; i++ {
+		_, ok := c.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.False(t, ok)
+	}
+	for i := 5; i < 15; i++ {
+		actual, ok := c.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.True(t, ok)
+		assert.Equal(t, "new", actual)
+	}
+
+	// src is left empty and usable.
+	assert.True(t, src.IsEmpty())
+	src.Put(NewStringKey("k1"), 1)
+	_, ok := c.Get(NewStringKey("k1"))
+	assert.False(t, ok)
+}
+
+func TestReplaceAllIncompatible(t *testing.T) {
+	c, _ := New()
+
+	assert.ErrorIs(t, c.ReplaceAll(c), ccmap.ErrIncompatibleMap)
+}
+
+func TestReplaceAllClosesWorkers(t *testing.T) {
+	m, _ := New(WithRehashMode(RehashBackground))
+	src, _ := New(WithRehashMode(RehashBackground))
+	c := m.(*concurrentHashMap)
+	defer c.Close()
+	defer src.Close()
+
+	old := c.load()
+	assert.NoError(t, c.ReplaceAll(src))
+	for _, s := range old.segments {
+		assert.Nil(t, s.worker)
+	}
+	for _, s := range c.load().segments {
+		assert.NotNil(t, s.worker)
+	}
+}
+
+// TestReplaceAllConcurrent checks that readers never see a mix of the old
+// and the new contents, run it with -race.
+func TestReplaceAllConcurrent(t *testing.T) {
+	const n = 1000
+
+	c, _ := New()
+	for i := 0; i < n; i++ {
+		c.Put(NewStringKey(fmt.Sprintf("%d", i)), 0)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				first, seen := -1, 0
+				c.Range(func(k Key, val interface{}) bool {
+					if first < 0 {
+						first = val.(int)
+					}
+					assert.Equal(t, first, val.(int))
+					seen++
+					return true
+				})
+				assert.Equal(t, n, seen)
+			}
+		}()
+	}
+
+	for gen := 1; gen <= 20; gen++ {
+		src, _ := New()
+		for i := 0; i < n; i++ {
+			src.Put(NewStringKey(fmt.Sprintf("%d", i)), gen)
+		}
+		assert.NoError(t, c.ReplaceAll(src))
+	}
+	close(done)
+	wg.Wait()
+
+	actual, ok := c.Get(NewStringKey("0"))
+	assert.True(t, ok)
+	assert.Equal(t, 20, actual)
+}
@@ -0,0 +1,117 @@
+package v1
+
+import (
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
+
+// PutIfAbsent puts <key, val> only if key is not present.
+// It returns true if val was stored.
+func (h *hashMap) PutIfAbsent(key Key, val interface{}) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, ok := h.lookup(key); ok {
+		return false
+	}
+	return h.put(key, val)
+}
+
+// Replace replaces the value of key only if key is present, keeping the
+// deadline of its entry, see PutWithTTL.
+// It returns the previous value and true if it was replaced.
+func (h *hashMap) Replace(key Key, val interface{}) (interface{}, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	en, ok := h.lookup(key)
+	if !ok {
+		return nil, false
+	}
+	prev := en.Value()
+	h.update(en, val)
+	return prev, true
+}
+
+// CompareAndSwap replaces the value of key with new only if it equals old,
+// keeping the deadline of its entry, see PutWithTTL.
+func (h *hashMap) CompareAndSwap(key Key, old, new interface{}) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	en, ok := h.lookup(key)
+	if !ok || en.Value() != old {
+		return false
+	}
+	return h.update(en, new)
+}
+
+// CompareAndDelete deletes key only if its value equals old.
+func (h *hashMap) CompareAndDelete(key Key, old interface{}) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	en, ok := h.lookup(key)
+	if !ok || en.Value() != old {
+		return false
+	}
+	_, ok = h.remove(key)
+	return ok
+}
+
+// LoadOrStore returns the value of key if present, otherwise it stores
+// and returns val.
+func (h *hashMap) LoadOrStore(key Key, val interface{}) (interface{}, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if en, ok := h.lookup(key); ok {
+		return en.Value(), true
+	}
+	if !h.put(key, val) {
+		return nil, false
+	}
+	return val, false
+}
+
+// LoadAndDelete deletes key and returns its previous value.
+func (h *hashMap) LoadAndDelete(key Key) (interface{}, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	en, ok := h.remove(key)
+	if !ok {
+		return nil, false
+	}
+	return en.Value(), true
+}
+
+// PutIfAbsent puts <key, val> only if key is not present.
+func (c *concurrentHashMap) PutIfAbsent(key Key, val interface{}) bool {
+	return c.segmentOf(key).PutIfAbsent(key, val)
+}
+
+// Replace replaces the value of key only if key is present.
+func (c *concurrentHashMap) Replace(key Key, val interface{}) (interface{}, bool) {
+	return c.segmentOf(key).Replace(key, val)
+}
+
+// CompareAndSwap replaces the value of key with new only if it equals old.
+func (c *concurrentHashMap) CompareAndSwap(key Key, old, new interface{}) bool {
+	return c.segmentOf(key).CompareAndSwap(key, old, new)
+}
+
+// CompareAndDelete deletes key only if its value equals old.
+func (c *concurrentHashMap) CompareAndDelete(key Key, old interface{}) bool {
+	return c.segmentOf(key).CompareAndDelete(key, old)
+}
+
+// LoadOrStore returns the value of key if present, otherwise it stores
+// and returns val.
+func (c *concurrentHashMap) LoadOrStore(key Key, val interface{}) (interface{}, bool) {
+	return c.segmentOf(key).LoadOrStore(key, val)
+}
+
+// LoadAndDelete deletes key and returns its previous value.
+func (c *concurrentHashMap) LoadAndDelete(key Key) (interface{}, bool) {
+	return c.segmentOf(key).LoadAndDelete(key)
+}
@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/csimplestring/go-concurrent-map/ccmap/maptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapSuite(t *testing.T) {
+	newMaps := []struct {
+		name   string
+		newMap func() ccmap.Map
+	}{
+		{"HashMap", func() ccmap.Map { m, _ := NewHashMap(4); return m }},
+		{"ConcurrentMap", func() ccmap.Map { m, _ := NewConcurrentMap(8); return m }},
+		{"OpenAddressing", func() ccmap.Map { m, _ := New(WithTableLayout(LayoutOpenAddressing)); return m }},
+	}
+	for _, tt := range newMaps {
+		t.Run(tt.name, func(t *testing.T) {
+			maptest.Run(t, tt.newMap)
+		})
+	}
+}
+
+func TestCompoundWhileRehashing(t *testing.T) {
+	m := newRehashingMap(t)
+	size := m.Size()
+
+	for i := 0; i < size; i++ {
+		k := NewStringKey(fmt.Sprintf("r%d", i))
+		assert.False(t, m.PutIfAbsent(k, -1))
+		assert.True(t, m.CompareAndSwap(k, i, i+1))
+	}
+	assert.Equal(t, size, m.Size())
+
+	for i := 0; i < size; i++ {
+		actual, loaded := m.LoadAndDelete(NewStringKey(fmt.Sprintf("r%d", i)))
+		assert.True(t, loaded)
+		assert.Equal(t, i+1, actual)
+	}
+	assert.Equal(t, 0, m.Size())
+}
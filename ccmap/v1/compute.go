@@ -0,0 +1,123 @@
+package v1
+
+import (
+	"reflect"
+
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
+
+// Compute updates the value of key with f, under the write lock of the
+// segment key belongs to. f receives the current value and whether key is
+// present; it returns the new value and whether to keep it. If keep is
+// false, key is deleted. If the value is unchanged, nothing is written;
+// otherwise an updated entry keeps its deadline, see PutWithTTL. Compute
+// returns the new value and true if it was kept, otherwise nil and false.
+//
+// f must not access the map, as the segment is locked while it runs.
+func (h *hashMap) Compute(key Key, f func(old interface{}, loaded bool) (new interface{}, keep bool)) (interface{}, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.compute(key, f)
+}
+
+// ComputeIfAbsent stores the value returned by f if key is not present.
+// It returns the current value of key and whether key is present after
+// the call.
+func (h *hashMap) ComputeIfAbsent(key Key, f func() (new interface{}, keep bool)) (interface{}, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.compute(key, func(old interface{}, loaded bool) (interface{}, bool) {
+		if loaded {
+			return old, true
+		}
+		return f()
+	})
+}
+
+// ComputeIfPresent updates the value of key with f if key is present.
+// It returns the new value and true if it was kept.
+func (h *hashMap) ComputeIfPresent(key Key, f func(old interface{}) (new interface{}, keep bool)) (interface{}, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.compute(key, func(old interface{}, loaded bool) (interface{}, bool) {
+		if !loaded {
+			return nil, false
+		}
+		return f(old)
+	})
+}
+
+// Merge stores val if key is not present, otherwise it replaces the
+// current value with the result of f. It returns the new value and true
+// if it was kept.
+func (h *hashMap) Merge(key Key, val interface{}, f func(old, val interface{}) (new interface{}, keep bool)) (interface{}, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.compute(key, func(old interface{}, loaded bool) (interface{}, bool) {
+		if !loaded {
+			return val, true
+		}
+		return f(old, val)
+	})
+}
+
+// compute implements Compute. The caller must hold the write lock.
+func (h *hashMap) compute(key Key, f func(old interface{}, loaded bool) (interface{}, bool)) (interface{}, bool) {
+	var old interface{}
+	en, loaded := h.lookup(key)
+	if loaded {
+		old = en.Value()
+	}
+
+	val, keep := f(old, loaded)
+	if !keep {
+		if loaded {
+			h.remove(key)
+		}
+		return nil, false
+	}
+
+	var ok bool
+	switch {
+	case !loaded:
+		ok = h.put(key, val)
+	case sameValue(old, val):
+		ok = true
+	default:
+		ok = h.update(en, val)
+	}
+	if !ok {
+		return nil, false
+	}
+	return val, true
+}
+
+// sameValue returns true if a equals b, false if they cannot be compared.
+func sameValue(a, b interface{}) bool {
+	return reflect.ValueOf(a).Comparable() && a == b
+}
+
+// Compute updates the value of key with f, see hashMap.Compute.
+func (c *concurrentHashMap) Compute(key Key, f func(old interface{}, loaded bool) (new interface{}, keep bool)) (interface{}, bool) {
+	return c.segmentOf(key).Compute(key, f)
+}
+
+// ComputeIfAbsent stores the value returned by f if key is not present.
+func (c *concurrentHashMap) ComputeIfAbsent(key Key, f func() (new interface{}, keep bool)) (interface{}, bool) {
+	return c.segmentOf(key).ComputeIfAbsent(key, f)
+}
+
+// ComputeIfPresent updates the value of key with f if key is present.
+func (c *concurrentHashMap) ComputeIfPresent(key Key, f func(old interface{}) (new interface{}, keep bool)) (interface{}, bool) {
+	return c.segmentOf(key).ComputeIfPresent(key, f)
+}
+
+// Merge stores val if key is not present, otherwise it merges it with
+// the current value by f.
+func (c *concurrentHashMap) Merge(key Key, val interface{}, f func(old, val interface{}) (new interface{}, keep bool)) (interface{}, bool) {
+	return c.segmentOf(key).Merge(key, val, f)
+}
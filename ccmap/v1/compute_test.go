@@ -0,0 +1,222 @@
+package v1
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompute(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+	k := NewStringKey("k1")
+
+	actual, ok := m.Compute(k, func(old interface{}, loaded bool) (interface{}, bool) {
+		assert.False(t, loaded)
+		assert.Nil(t, old)
+		return 1, true
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 1, actual)
+
+	actual, ok = m.Compute(k, func(old interface{}, loaded bool) (interface{}, bool) {
+		assert.True(t, loaded)
+		return old.(int) + 1, true
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 2, actual)
+
+	actual, _ = m.Get(k)
+	assert.Equal(t, 2, actual)
+}
+
+func TestComputeUnchanged(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+	k := NewStringKey("k1")
+	m.Put(k, 1)
+	hits := m.Stats().PutHits
+
+	actual, ok := m.Compute(k, func(old interface{}, loaded bool) (interface{}, bool) {
+		return old, true
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 1, actual)
+	assert.Equal(t, hits, m.Stats().PutHits)
+
+	// values that cannot be compared are always written.
+	m.Put(k, []int{1})
+	hits = m.Stats().PutHits
+	m.Compute(k, func(old interface{}, loaded bool) (interface{}, bool) {
+		return old, true
+	})
+	assert.Equal(t, hits+1, m.Stats().PutHits)
+}
+
+func TestComputeKeepsTTL(t *testing.T) {
+	clock := newFakeClock()
+	m := newExpiringMap(t, clock)
+	k := NewStringKey("k1")
+	m.PutWithTTL(k, 1, time.Minute)
+
+	// neither an unchanged nor an updated value extends the TTL.
+	m.Compute(k, func(old interface{}, loaded bool) (interface{}, bool) {
+		return old, true
+	})
+	m.Compute(k, func(old interface{}, loaded bool) (interface{}, bool) {
+		return old.(int) + 1, true
+	})
+	actual, ok := m.Get(k)
+	assert.True(t, ok)
+	assert.Equal(t, 2, actual)
+
+	clock.Advance(time.Minute)
+	_, ok = m.Get(k)
+	assert.False(t, ok)
+}
+
+func TestComputeDelete(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+	k := NewStringKey("k1")
+
+	// nothing to delete, nothing stored.
+	actual, ok := m.Compute(k, func(old interface{}, loaded bool) (interface{}, bool) {
+		return 1, false
+	})
+	assert.False(t, ok)
+	assert.Nil(t, actual)
+	_, ok = m.Get(k)
+	assert.False(t, ok)
+
+	m.Put(k, 1)
+	actual, ok = m.Compute(k, func(old interface{}, loaded bool) (interface{}, bool) {
+		return nil, false
+	})
+	assert.False(t, ok)
+	assert.Nil(t, actual)
+	_, ok = m.Get(k)
+	assert.False(t, ok)
+}
+
+func TestComputeIfAbsent(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+	k := NewStringKey("k1")
+
+	actual, ok := m.ComputeIfAbsent(k, func() (interface{}, bool) {
+		return nil, false
+	})
+	assert.False(t, ok)
+	assert.Nil(t, actual)
+
+	actual, ok = m.ComputeIfAbsent(k, func() (interface{}, bool) {
+		return 1, true
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 1, actual)
+
+	actual, ok = m.ComputeIfAbsent(k, func() (interface{}, bool) {
+		t.Error("f must not run for a present key")
+		return 2, true
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 1, actual)
+}
+
+func TestComputeIfPresent(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+	k := NewStringKey("k1")
+
+	actual, ok := m.ComputeIfPresent(k, func(old interface{}) (interface{}, bool) {
+		t.Error("f must not run for an absent key")
+		return 1, true
+	})
+	assert.False(t, ok)
+	assert.Nil(t, actual)
+	_, ok = m.Get(k)
+	assert.False(t, ok)
+
+	m.Put(k, 1)
+	actual, ok = m.ComputeIfPresent(k, func(old interface{}) (interface{}, bool) {
+		return old.(int) * 10, true
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 10, actual)
+
+	_, ok = m.ComputeIfPresent(k, func(old interface{}) (interface{}, bool) {
+		return nil, false
+	})
+	assert.False(t, ok)
+	_, ok = m.Get(k)
+	assert.False(t, ok)
+}
+
+func TestMerge(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+	k := NewStringKey("k1")
+	concat := func(old, val interface{}) (interface{}, bool) {
+		return append(old.([]string), val.([]string)...), true
+	}
+
+	actual, ok := m.Merge(k, []string{"a"}, concat)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a"}, actual)
+
+	actual, ok = m.Merge(k, []string{"b"}, concat)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a", "b"}, actual)
+
+	_, ok = m.Merge(k, nil, func(old, val interface{}) (interface{}, bool) {
+		return nil, false
+	})
+	assert.False(t, ok)
+	_, ok = m.Get(k)
+	assert.False(t, ok)
+}
+
+func TestComputeHotKey(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+	counter := NewStringKey("counter")
+	list := NewStringKey("list")
+
+	var wg sync.WaitGroup
+	for g := 0; g < 32; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				m.Compute(counter, func(old interface{}, loaded bool) (interface{}, bool) {
+					if !loaded {
+						return 1, true
+					}
+					return old.(int) + 1, true
+				})
+				m.Merge(list, []int{g}, func(old, val interface{}) (interface{}, bool) {
+					return append(old.([]int), val.([]int)...), true
+				})
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	actual, _ := m.Get(counter)
+	assert.Equal(t, 32*200, actual)
+
+	actual, _ = m.Get(list)
+	assert.Equal(t, 32*200, len(actual.([]int)))
+}
+
+func TestComputeWhileRehashing(t *testing.T) {
+	m := newRehashingMap(t)
+	size := m.Size()
+
+	for i := 0; i < size; i++ {
+		actual, ok := m.ComputeIfPresent(NewStringKey(fmt.Sprintf("r%d", i)), func(old interface{}) (interface{}, bool) {
+			return old.(int) + 100, true
+		})
+		assert.True(t, ok)
+		assert.Equal(t, i+100, actual)
+	}
+	assert.Equal(t, size, m.Size())
+}
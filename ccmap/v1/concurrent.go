@@ -2,6 +2,8 @@ package v1
 
 import (
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/csimplestring/go-concurrent-map/ccmap"
 	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
@@ -11,49 +13,145 @@ const (
 	MAX_SEGMENTS = 65536
 )
 
+// ConcurrentMap is a ccmap.Map split into segments that can be
+// accessed in parallel.
+type ConcurrentMap interface {
+	ccmap.Map
+
+	// Compute, ComputeIfAbsent, ComputeIfPresent and Merge update the
+	// value of a key atomically by running a function while the segment
+	// of the key is locked. If the function returns keep == false the key
+	// is deleted. They return the resulting value and whether the key is
+	// present after the call. The function must not access the map.
+	Compute(k Key, f func(old interface{}, loaded bool) (new interface{}, keep bool)) (interface{}, bool)
+	ComputeIfAbsent(k Key, f func() (new interface{}, keep bool)) (interface{}, bool)
+	ComputeIfPresent(k Key, f func(old interface{}) (new interface{}, keep bool)) (interface{}, bool)
+	Merge(k Key, val interface{}, f func(old, val interface{}) (new interface{}, keep bool)) (interface{}, bool)
+
+	// PutAll, GetMany and DeleteMany run Put, Get and Delete for many keys
+	// at once. They return the result of each key at its index. PutAll and
+	// DeleteMany lock each segment once, GetMany takes no lock.
+	PutAll(entries []Entry) []bool
+	GetMany(keys []Key) ([]interface{}, []bool)
+	DeleteMany(keys []Key) []bool
+
+	// PutWithTTL puts val for k with an entry that expires after ttl,
+	// see hashMap.PutWithTTL.
+	PutWithTTL(k Key, val interface{}, ttl time.Duration) bool
+
+	// Stats returns a snapshot of the statistics of every segment.
+	Stats() Stats
+
+	// Clear deletes all the entries, see concurrentHashMap.Clear.
+	Clear()
+	// ReplaceAll atomically replaces all the entries with those of src,
+	// see concurrentHashMap.ReplaceAll.
+	ReplaceAll(src ConcurrentMap) error
+
+	// Close stops the janitor and the background rehash workers, if any.
+	// The map stays usable, it falls back to inline rehashing and expired
+	// entries are still deleted when found.
+	Close()
+}
+
 type concurrentHashMap struct {
+	// current holds the segments, ReplaceAll swaps it as a whole.
+	current atomic.Pointer[segmentSet]
+	// janitor deletes expired entries if WithJanitor is set, nil otherwise.
+	janitor *janitor
+}
+
+// segmentSet is the set of segments of a concurrentHashMap, with what it
+// takes to find the segment of a key.
+type segmentSet struct {
 	segmentShift uint
 	segmentMask  int
 	segments     []*hashMap
-
-	stat map[int]int
+	// hasher and seed hash the keys, all segments share them.
+	hasher Hasher
+	seed   uint64
+	// cfg is the configuration the segments were created with.
+	cfg config
 }
 
-func NewConcurrentMap(concurrencyLevel int) (ccmap.Map, error) {
+func NewConcurrentMap(concurrencyLevel int) (ConcurrentMap, error) {
 	if concurrencyLevel > MAX_SEGMENTS {
 		concurrencyLevel = MAX_SEGMENTS
 	}
 
-	sshift := 0
 	ssize := 1
 	for ssize < concurrencyLevel {
-		sshift = sshift + 1
 		ssize = ssize << 1
 	}
 
+	return New(WithSegments(ssize))
+}
+
+// New creates a concurrent map configured by opts. Unlike
+// NewConcurrentMap it never adjusts invalid settings, it returns an error
+// describing them instead, which wraps ccmap.ErrInvalidConfig,
+// ccmap.ErrInvalidCapacity or ccmap.ErrCapacityExceeded.
+func New(opts ...Option) (ConcurrentMap, error) {
+	cfg, err := newConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := newSegmentSet(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &concurrentHashMap{}
+	c.current.Store(set)
+	if cfg.janitorInterval > 0 {
+		c.startJanitor(cfg.janitorInterval)
+	}
+	return c, nil
+}
+
+// newSegmentSet creates empty segments, cfg must be valid.
+func newSegmentSet(cfg config) (*segmentSet, error) {
+	sshift := 0
+	for 1<<sshift < cfg.segments {
+		sshift++
+	}
+
 	segmentShift := 32 - sshift
-	segmentMask := ssize - 1
-
-	var err error
-	segments := make([]*hashMap, ssize)
-	statMap := make(map[int]int)
-	for i := 0; i < ssize; i++ {
-		statMap[i] = 0
-		segments[i], err = newHashMap(16)
+	segmentMask := cfg.segments - 1
+
+	size, err := cfg.segmentSize()
+	if err != nil {
+		return nil, err
+	}
+
+	segCfg := cfg
+	if cfg.maxEntries > 0 {
+		segCfg.maxEntries = (cfg.maxEntries + cfg.segments - 1) / cfg.segments
+	}
+
+	segments := make([]*hashMap, cfg.segments)
+	for i := range segments {
+		segments[i], err = newHashMapConfig(size, segCfg)
 		if err != nil {
+			for _, s := range segments[:i] {
+				s.Close()
+			}
 			return nil, err
 		}
 	}
 
-	return &concurrentHashMap{
+	return &segmentSet{
 		segmentMask:  segmentMask,
 		segmentShift: (uint)(segmentShift),
 		segments:     segments,
-		stat:         statMap,
+		hasher:       cfg.hasher,
+		seed:         cfg.seed,
+		cfg:          cfg,
 	}, nil
 }
 
-func (c *concurrentHashMap) hash(h int) int {
+func (set *segmentSet) hash(h int) int {
 	h += (h << 15) ^ 0xffffcd7d
 	h ^= (h >> 10)
 	h += (h << 3)
@@ -62,24 +160,134 @@ func (c *concurrentHashMap) hash(h int) int {
 	return h ^ (h >> 16)
 }
 
-func (c *concurrentHashMap) segmentFor(hash int) int {
-	return (hash >> c.segmentShift) & c.segmentMask
+func (set *segmentSet) segmentFor(hash int) int {
+	return (hash >> set.segmentShift) & set.segmentMask
+}
+
+// segmentOf returns the segment key belongs to.
+func (set *segmentSet) segmentOf(key Key) *hashMap {
+	return set.segments[set.segmentFor(set.hash(set.hasher(key, set.seed)))]
+}
+
+// load returns the current segments of c. Methods visiting all the
+// segments load them once, so they never mix the contents of c from
+// before and after ReplaceAll.
+func (c *concurrentHashMap) load() *segmentSet {
+	return c.current.Load()
+}
+
+// segmentOf returns the segment key belongs to.
+func (c *concurrentHashMap) segmentOf(key Key) *hashMap {
+	return c.load().segmentOf(key)
 }
 
 func (c *concurrentHashMap) Put(key Key, val interface{}) bool {
-	slot := c.segmentFor(c.hash(key.Hash()))
-	c.stat[slot]++
-	return c.segments[slot].Put(key, val)
+	return c.segmentOf(key).Put(key, val)
+}
+
+// TryPut puts <key, val> like Put, see hashMap.TryPut.
+func (c *concurrentHashMap) TryPut(key Key, val interface{}) error {
+	return c.segmentOf(key).TryPut(key, val)
 }
 
 func (c *concurrentHashMap) Get(key Key) (interface{}, bool) {
-	return c.segments[c.segmentFor(c.hash(key.Hash()))].Get(key)
+	return c.segmentOf(key).Get(key)
 }
 
 func (c *concurrentHashMap) Delete(key Key) bool {
-	return false
+	return c.segmentOf(key).Delete(key)
+}
+
+// Len returns the number of entries, see ccmap.Map. It sums the counters
+// of the segments, so it costs O(segments) and takes no lock.
+func (c *concurrentHashMap) Len() int {
+	n := 0
+	for _, s := range c.load().segments {
+		n += s.Len()
+	}
+	return n
+}
+
+// IsEmpty returns true if c has no entry.
+func (c *concurrentHashMap) IsEmpty() bool {
+	for _, s := range c.load().segments {
+		if !s.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Range calls f for each key and value in c, see ccmap.Map.
+// Segments are copied one at a time, so c is never locked as a whole.
+func (c *concurrentHashMap) Range(f func(k Key, val interface{}) bool) {
+	for _, s := range c.load().segments {
+		for _, en := range s.snapshot() {
+			if !f(en.k, en.v) {
+				return
+			}
+		}
+	}
+}
+
+// Clear deletes all the entries of c. Every segment is reset to its
+// initial size, one after another, so entries put concurrently may
+// survive. Use ReplaceAll to drop all the entries at once.
+func (c *concurrentHashMap) Clear() {
+	for _, s := range c.load().segments {
+		s.Clear()
+	}
+}
+
+// ReplaceAll atomically replaces the contents of c with those of src,
+// which must have been created by New or NewConcurrentMap and is left
+// empty. Readers of c see either the old or the new contents, never a
+// mix of both. Writes to c that run concurrently may be applied to the
+// old contents, and be lost.
+func (c *concurrentHashMap) ReplaceAll(src ConcurrentMap) error {
+	other, ok := src.(*concurrentHashMap)
+	if !ok || other == c {
+		return fmt.Errorf("%w: cannot replace the contents of a map with %T",
+			ccmap.ErrIncompatibleMap, src)
+	}
+
+	fresh, err := newSegmentSet(other.load().cfg)
+	if err != nil {
+		return err
+	}
+
+	old := c.current.Swap(other.current.Swap(fresh))
+	old.close()
+	return nil
 }
 
-func (c *concurrentHashMap) Stat() {
-	fmt.Print(c.stat)
+// Close stops the janitor and the background rehash workers of all
+// segments.
+func (c *concurrentHashMap) Close() {
+	if c.janitor != nil {
+		c.janitor.stop()
+	}
+	c.load().close()
+}
+
+// close stops the background rehash workers of all segments.
+func (set *segmentSet) close() {
+	for _, s := range set.segments {
+		s.Close()
+	}
+}
+
+// Stats returns a snapshot of the statistics of every segment.
+// Segments are visited one after another, so the snapshot is not
+// atomic across segments.
+func (c *concurrentHashMap) Stats() Stats {
+	segments := c.load().segments
+	st := Stats{
+		Segments: make([]SegmentStats, len(segments)),
+	}
+	for i, s := range segments {
+		st.Segments[i] = s.Stats()
+		st.add(st.Segments[i])
+	}
+	return st
 }
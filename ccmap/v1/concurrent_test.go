@@ -2,8 +2,13 @@ package v1
 
 import (
 	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"unsafe"
 
+	"github.com/csimplestring/go-concurrent-map/ccmap"
 	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
 	"github.com/stretchr/testify/assert"
 )
@@ -13,6 +18,35 @@ func TestNewConcurrentMap(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestNewConcurrentMapSegments(t *testing.T) {
+	m, err := NewConcurrentMap(5)
+	assert.Nil(t, err)
+	assert.Len(t, m.(*concurrentHashMap).load().segments, 8)
+
+	m, err = NewConcurrentMap(MAX_SEGMENTS * 2)
+	assert.Nil(t, err)
+	assert.Len(t, m.(*concurrentHashMap).load().segments, MAX_SEGMENTS)
+}
+
+func TestNewSeed(t *testing.T) {
+	m, err := New(WithSegments(8), WithSeed(42))
+	assert.Nil(t, err)
+
+	c := m.(*concurrentHashMap)
+	assert.Equal(t, uint64(42), c.load().seed)
+	for _, s := range c.load().segments {
+		assert.Equal(t, uint64(42), s.seed)
+	}
+}
+
+func TestCCHashMapResistsCollisions(t *testing.T) {
+	m, _ := NewConcurrentMap(1)
+	for i, k := range collisionSet(32, 64) {
+		m.Put(k, i)
+	}
+	assert.True(t, m.Stats().LongestChain < 10, "chain %d", m.Stats().LongestChain)
+}
+
 func TestCCHashMapPut(t *testing.T) {
 	m, _ := NewConcurrentMap(4)
 
@@ -20,7 +54,7 @@ func TestCCHashMapPut(t *testing.T) {
 		key := NewStringKey(fmt.Sprintf("%d", i))
 		m.Put(key, i)
 	}
-	m.(*concurrentHashMap).Stat()
+	assert.Equal(t, 4, m.Stats().Entries)
 }
 
 func TestCCHashMapPut2(t *testing.T) {
@@ -74,6 +108,268 @@ func TestCCHashMapGetFail(t *testing.T) {
 	}
 }
 
+func TestCCHashMapDeleteOK(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+
+	for i := 0; i < 30; i++ {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		m.Put(key, i)
+	}
+
+	for i := 0; i < 30; i++ {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		ok := m.Delete(key)
+		assert.True(t, ok, "key %d", i)
+	}
+
+	for i := 0; i < 30; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.False(t, ok)
+		assert.Nil(t, actual)
+	}
+}
+
+func TestCCHashMapDeleteFail(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+
+	for i := 0; i < 30; i++ {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		m.Put(key, i)
+	}
+
+	for i := 31; i < 60; i++ {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		ok := m.Delete(key)
+		assert.False(t, ok)
+	}
+
+	for i := 0; i < 30; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+	}
+}
+
+func TestCCHashMapDeleteTwice(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+
+	key := NewStringKey("k1")
+	m.Put(key, 1)
+
+	assert.True(t, m.Delete(key))
+	assert.False(t, m.Delete(key))
+
+	m.Put(key, 2)
+	actual, ok := m.Get(key)
+	assert.True(t, ok)
+	assert.Equal(t, 2, actual)
+}
+
+func TestCCHashMapDeleteWhileRehashing(t *testing.T) {
+	m, _ := NewConcurrentMap(4)
+
+	for i := 0; i < 1000; i++ {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		m.Put(key, i)
+	}
+
+	for i := 0; i < 1000; i += 2 {
+		key := NewStringKey(fmt.Sprintf("%d", i))
+		assert.True(t, m.Delete(key), "key %d", i)
+	}
+
+	for i := 0; i < 1000; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		if i%2 == 0 {
+			assert.False(t, ok, "key %d", i)
+			assert.Nil(t, actual)
+		} else {
+			assert.True(t, ok, "key %d", i)
+			assert.Equal(t, i, actual)
+		}
+	}
+}
+
+func TestCCHashMapMixedKeys(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+
+	keys := []Key{
+		NewStringKey("1"),
+		NewIntKey(1),
+		NewInt64Key(1),
+		NewUint64Key(1),
+		NewBytesKey([]byte("1")),
+		NewUUIDKey([16]byte{1}),
+		NewCompositeKey(NewIntKey(1)),
+		Of(struct{ a, b int }{1, 1}),
+	}
+	for i, k := range keys {
+		assert.True(t, m.PutIfAbsent(k, i), "key %T", k)
+	}
+
+	for i, k := range keys {
+		actual, ok := m.Get(k)
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+	}
+	assert.Equal(t, len(keys), m.Stats().Entries)
+}
+
+func TestCCHashMapStats(t *testing.T) {
+	m, _ := NewConcurrentMap(4)
+
+	for i := 0; i < 100; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+	for i := 0; i < 50; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+	for i := 0; i < 120; i++ {
+		m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+	}
+	for i := 90; i < 110; i++ {
+		m.Delete(NewStringKey(fmt.Sprintf("%d", i)))
+	}
+
+	st := m.Stats()
+	assert.Equal(t, 4, len(st.Segments))
+	assert.Equal(t, 90, st.Entries)
+	assert.Equal(t, int64(50), st.PutHits)
+	assert.Equal(t, int64(100), st.PutMisses)
+	assert.Equal(t, int64(100), st.GetHits)
+	assert.Equal(t, int64(20), st.GetMisses)
+	assert.Equal(t, int64(10), st.DeleteHits)
+	assert.Equal(t, int64(10), st.DeleteMisses)
+	assert.True(t, st.Rehashes > 0)
+	assert.True(t, st.LongestChain > 0)
+	assert.InDelta(t, float64(st.Entries)/float64(st.Buckets), st.LoadFactor, 1e-9)
+
+	entries := 0
+	for _, s := range st.Segments {
+		entries += s.Entries
+		assert.True(t, s.LongestChain <= st.LongestChain)
+	}
+	assert.Equal(t, st.Entries, entries)
+}
+
+func TestCCHashMapStatsConcurrent(t *testing.T) {
+	m, _ := NewConcurrentMap(4)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := NewStringKey(fmt.Sprintf("%d-%d", g, i))
+				m.Put(key, i)
+				m.Get(key)
+				m.Stats()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	st := m.Stats()
+	assert.Equal(t, 4000, st.Entries)
+	assert.Equal(t, int64(4000), st.PutMisses)
+	assert.Equal(t, int64(4000), st.GetHits)
+}
+
+// TestSegmentFootprint checks that the Get counters of a segment do not
+// grow with the number of CPUs beyond MAX_GET_STRIPES.
+func TestSegmentFootprint(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(64))
+
+	m, err := NewConcurrentMap(MAX_SEGMENTS)
+	assert.NoError(t, err)
+	stripes := len(m.(*concurrentHashMap).load().segments[0].counters.gets.stripes)
+	bytes := uintptr(stripes) * unsafe.Sizeof(hitStripe{})
+
+	t.Logf("%d segments with 64 CPUs: %d bytes of Get counters per segment", MAX_SEGMENTS, bytes)
+	assert.Equal(t, MAX_GET_STRIPES, stripes)
+	assert.True(t, bytes <= 512, "%d bytes per segment", bytes)
+}
+
+func TestCCHashMapRange(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+
+	for i := 0; i < 1000; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+
+	visited := make(map[string]interface{})
+	m.Range(func(k Key, v interface{}) bool {
+		_, dup := visited[k.String()]
+		assert.False(t, dup, "key %s", k)
+		visited[k.String()] = v
+		return true
+	})
+
+	assert.Equal(t, 1000, len(visited))
+	for i := 0; i < 1000; i++ {
+		assert.Equal(t, i, visited[fmt.Sprintf("%d", i)])
+	}
+}
+
+func TestCCHashMapRangeStop(t *testing.T) {
+	m, _ := NewConcurrentMap(8)
+
+	for i := 0; i < 1000; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+
+	cnt := 0
+	m.Range(func(k Key, v interface{}) bool {
+		cnt++
+		return cnt < 10
+	})
+	assert.Equal(t, 10, cnt)
+}
+
+func TestCCHashMapRangeConcurrent(t *testing.T) {
+	m, _ := NewConcurrentMap(4)
+
+	for i := 0; i < 1000; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("stable-%d", i)), i)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				key := NewStringKey(fmt.Sprintf("%d-%d", g, i))
+				m.Put(key, i)
+				if i%2 == 0 {
+					m.Delete(key)
+				}
+			}
+		}(g)
+	}
+
+	for r := 0; r < 10; r++ {
+		stable := 0
+		m.Range(func(k Key, v interface{}) bool {
+			if len(k.String()) > 7 && k.String()[:7] == "stable-" {
+				stable++
+			}
+			return true
+		})
+		assert.Equal(t, 1000, stable)
+	}
+
+	close(done)
+	wg.Wait()
+}
+
 func BenchmarkCCHashMapPut(b *testing.B) {
 	m, _ := NewConcurrentMap(8)
 
@@ -81,3 +377,54 @@ func BenchmarkCCHashMapPut(b *testing.B) {
 		m.Put(k, i)
 	}
 }
+
+// rwMutexMap is a concurrent map whose Get takes the shared lock of the
+// segment, as it did before reads became lock-free.
+type rwMutexMap struct {
+	*concurrentHashMap
+}
+
+func (m rwMutexMap) Get(key Key) (interface{}, bool) {
+	s := m.segmentOf(key)
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	en, ok := s.find(key)
+	if !ok {
+		return nil, false
+	}
+	return en.Value(), true
+}
+
+// benchmarkReadHeavy runs two reads per write in parallel, the ratio of
+// readers to writers of bench/bench.go.
+func benchmarkReadHeavy(b *testing.B, m ccmap.Map) {
+	for i, k := range benchmarkKeys {
+		m.Put(k, i)
+	}
+
+	var seq atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(seq.Add(1)) * 7919
+		for pb.Next() {
+			k := benchmarkKeys[i%len(benchmarkKeys)]
+			if i%3 == 0 {
+				m.Put(k, i)
+			} else {
+				m.Get(k)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkCCHashMapReadHeavy(b *testing.B) {
+	m, _ := NewConcurrentMap(16)
+	benchmarkReadHeavy(b, m)
+}
+
+func BenchmarkCCHashMapReadHeavyRWMutex(b *testing.B) {
+	m, _ := NewConcurrentMap(16)
+	benchmarkReadHeavy(b, rwMutexMap{m.(*concurrentHashMap)})
+}
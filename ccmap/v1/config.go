@@ -0,0 +1,401 @@
+package v1
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
+
+const (
+	SEGMENTS_DEFAULT        = 16
+	MAX_LOAD_FACTOR_DEFAULT = 1.0
+	// MAX_OPEN_LOAD_FACTOR is the highest and default max load factor of
+	// LayoutOpenAddressing, whose slots hold one entry each.
+	MAX_OPEN_LOAD_FACTOR = 7.0 / 8
+	// MAX_OPEN_REHASH_LOAD caps the load factor of LayoutOpenAddressing
+	// while rehashing in background, see OVERLOAD_FACTOR.
+	MAX_OPEN_REHASH_LOAD    = 15.0 / 16
+	MIN_LOAD_FACTOR_DEFAULT = 0.1
+	GROWTH_FACTOR_DEFAULT   = 2
+	REHASH_BUDGET_DEFAULT   = 100 * time.Microsecond
+	// OVERLOAD_FACTOR times the max load factor is the load factor a
+	// table may reach while a background worker rehashes it, beyond that
+	// the writers help the worker.
+	OVERLOAD_FACTOR = 2
+)
+
+// RehashMode selects who moves the entries of a rehashing segment.
+type RehashMode int
+
+const (
+	// RehashInline lets every write move one bucket.
+	RehashInline RehashMode = iota
+	// RehashBackground hands the work to one goroutine per segment, so
+	// rehashing also completes when the map is idle. The map must be
+	// closed to stop the goroutines.
+	RehashBackground
+)
+
+// TableLayout selects how the tables of a map store their entries.
+type TableLayout int
+
+const (
+	// LayoutChained chains the entries of a bucket in a linked list.
+	LayoutChained TableLayout = iota
+	// LayoutOpenAddressing stores the entries right in slots, probed by
+	// groups of 8 like a Swiss table. Put allocates once instead of twice
+	// and Get does not chase pointers, but the load factor cannot exceed
+	// MAX_OPEN_LOAD_FACTOR.
+	LayoutOpenAddressing
+)
+
+// newTable creates a table of size buckets or slots.
+func (l TableLayout) newTable(size int) (table, error) {
+	if l == LayoutOpenAddressing {
+		return newSwissTable(size)
+	}
+	return newHtable(size)
+}
+
+// EvictionPolicy selects which entries a bounded map evicts, see
+// WithMaxEntries.
+type EvictionPolicy int
+
+const (
+	// PolicyLRU evicts the least recently used entry.
+	PolicyLRU EvictionPolicy = iota
+	// PolicyTinyLFU is W-TinyLFU: a new entry is only admitted in place
+	// of an older one if it is estimated to be used more often, so that
+	// keys read once, e.g. by a scan, do not push out popular ones. Each
+	// entry is tracked by a few bits of a frequency sketch, keyed by
+	// Key.Hash.
+	PolicyTinyLFU
+)
+
+// newPolicy creates a policy keeping at most capacity entries.
+func (p EvictionPolicy) newPolicy(capacity int) evictionPolicy {
+	if p == PolicyTinyLFU {
+		return newTinyLFUPolicy(capacity)
+	}
+	return newLRUPolicy(capacity)
+}
+
+// Hasher computes the hash of k under the seed of a map.
+type Hasher func(k Key, seed uint64) int
+
+// config holds the settings of a map, see the With options.
+type config struct {
+	segments        int
+	initialCapacity int
+	maxLoadFactor   float64
+	minLoadFactor   float64
+	growthFactor    int
+	layout          TableLayout
+	hasher          Hasher
+	seed            uint64
+	rehashMode      RehashMode
+	rehashBudget    time.Duration
+	clock           Clock
+	onExpire        func(k Key, val interface{})
+	janitorInterval time.Duration
+	// maxEntries bounds the entries of a segment, 0 if unbounded.
+	// newSegmentSet gives each segment its share of the entries of the map.
+	maxEntries int
+	policy     EvictionPolicy
+	onEvict    func(k Key, val interface{}, reason EvictionReason)
+}
+
+// defaultConfig returns the default settings, with a random seed.
+// maxLoadFactor is left to newConfig, it depends on the layout.
+func defaultConfig() config {
+	return config{
+		segments:      SEGMENTS_DEFAULT,
+		minLoadFactor: MIN_LOAD_FACTOR_DEFAULT,
+		growthFactor:  GROWTH_FACTOR_DEFAULT,
+		hasher:        HashOf,
+		seed:          rand.Uint64(),
+		rehashMode:    RehashInline,
+		rehashBudget:  REHASH_BUDGET_DEFAULT,
+		clock:         systemClock{},
+	}
+}
+
+// newConfig applies opts to the default settings and validates them.
+func newConfig(opts ...Option) (config, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return config{}, err
+		}
+	}
+
+	if cfg.maxLoadFactor == 0 {
+		cfg.maxLoadFactor = MAX_LOAD_FACTOR_DEFAULT
+		if cfg.layout == LayoutOpenAddressing {
+			cfg.maxLoadFactor = MAX_OPEN_LOAD_FACTOR
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		return config{}, err
+	}
+	return cfg, nil
+}
+
+// validate checks the settings that depend on each other.
+func (cfg config) validate() error {
+	if cfg.layout == LayoutOpenAddressing && cfg.maxLoadFactor > MAX_OPEN_LOAD_FACTOR {
+		return fmt.Errorf("%w: max load factor %g above %g with open addressing",
+			ccmap.ErrInvalidConfig, cfg.maxLoadFactor, MAX_OPEN_LOAD_FACTOR)
+	}
+	if cfg.maxEntries > 0 && cfg.maxEntries < cfg.segments {
+		return fmt.Errorf("%w: max entries %d below the number of segments %d",
+			ccmap.ErrInvalidConfig, cfg.maxEntries, cfg.segments)
+	}
+	// growing must not bring the load factor below the shrink threshold,
+	// otherwise the table would shrink right after growing.
+	if cfg.minLoadFactor >= cfg.maxLoadFactor/float64(cfg.growthFactor) {
+		return fmt.Errorf("%w: min load factor %g must be below max load factor / growth factor = %g",
+			ccmap.ErrInvalidConfig, cfg.minLoadFactor, cfg.maxLoadFactor/float64(cfg.growthFactor))
+	}
+	_, err := cfg.segmentSize()
+	return err
+}
+
+// maxRehashLoad returns the load factor beyond which writers help the
+// background worker, see OVERLOAD_FACTOR. An open-addressing table stays
+// below MAX_OPEN_REHASH_LOAD, its puts fail once it is full.
+func (cfg config) maxRehashLoad() float64 {
+	if cfg.layout == LayoutOpenAddressing {
+		return min(cfg.maxLoadFactor*OVERLOAD_FACTOR, MAX_OPEN_REHASH_LOAD)
+	}
+	return cfg.maxLoadFactor * OVERLOAD_FACTOR
+}
+
+// segmentSize returns the initial table size of each segment.
+func (cfg config) segmentSize() (int, error) {
+	if cfg.initialCapacity == 0 {
+		return ccmap.BUCKET_SIZE_DEFAULT, nil
+	}
+
+	perSegment := math.Ceil(float64(cfg.initialCapacity) / float64(cfg.segments))
+	size := math.Ceil(perSegment / cfg.maxLoadFactor)
+	if size > ccmap.MAX_TABLE_SIZE {
+		return 0, fmt.Errorf("%w: initial capacity %d needs %g buckets per segment, max %d",
+			ccmap.ErrCapacityExceeded, cfg.initialCapacity, size, ccmap.MAX_TABLE_SIZE)
+	}
+	return tableSizeFor(int(size))
+}
+
+// Option configures a map created by New.
+type Option func(*config) error
+
+// WithInitialCapacity sizes the tables to hold n entries without
+// rehashing. By default every segment starts with
+// ccmap.BUCKET_SIZE_DEFAULT buckets.
+func WithInitialCapacity(n int) Option {
+	return func(cfg *config) error {
+		if n < 0 {
+			return fmt.Errorf("%w: initial capacity %d is negative", ccmap.ErrInvalidCapacity, n)
+		}
+		cfg.initialCapacity = n
+		return nil
+	}
+}
+
+// WithSegments sets the number of segments, a power of two between 1 and
+// MAX_SEGMENTS. It defaults to SEGMENTS_DEFAULT.
+func WithSegments(n int) Option {
+	return func(cfg *config) error {
+		if n < 1 || n > MAX_SEGMENTS {
+			return fmt.Errorf("%w: segments %d out of range [1, %d]", ccmap.ErrInvalidConfig, n, MAX_SEGMENTS)
+		}
+		if n&(n-1) != 0 {
+			return fmt.Errorf("%w: segments %d is not a power of two", ccmap.ErrInvalidConfig, n)
+		}
+		cfg.segments = n
+		return nil
+	}
+}
+
+// WithMaxLoadFactor sets the number of entries per bucket above which a
+// segment grows. It defaults to MAX_LOAD_FACTOR_DEFAULT, or
+// MAX_OPEN_LOAD_FACTOR with LayoutOpenAddressing.
+func WithMaxLoadFactor(f float64) Option {
+	return func(cfg *config) error {
+		if !(f > 0) {
+			return fmt.Errorf("%w: max load factor %g must be positive", ccmap.ErrInvalidConfig, f)
+		}
+		cfg.maxLoadFactor = f
+		return nil
+	}
+}
+
+// WithMinLoadFactor sets the number of entries per bucket below which a
+// segment shrinks, never below its initial size. Zero disables
+// shrinking. It defaults to MIN_LOAD_FACTOR_DEFAULT.
+func WithMinLoadFactor(f float64) Option {
+	return func(cfg *config) error {
+		if !(f >= 0) {
+			return fmt.Errorf("%w: min load factor %g must not be negative", ccmap.ErrInvalidConfig, f)
+		}
+		cfg.minLoadFactor = f
+		return nil
+	}
+}
+
+// WithGrowthFactor sets how many times bigger a table gets when it
+// grows, a power of two of at least 2. It defaults to
+// GROWTH_FACTOR_DEFAULT.
+func WithGrowthFactor(n int) Option {
+	return func(cfg *config) error {
+		if n < 2 || n&(n-1) != 0 {
+			return fmt.Errorf("%w: growth factor %d is not a power of two >= 2", ccmap.ErrInvalidConfig, n)
+		}
+		cfg.growthFactor = n
+		return nil
+	}
+}
+
+// WithTableLayout selects how the tables store their entries.
+// It defaults to LayoutChained.
+func WithTableLayout(layout TableLayout) Option {
+	return func(cfg *config) error {
+		if layout != LayoutChained && layout != LayoutOpenAddressing {
+			return fmt.Errorf("%w: unknown table layout %d", ccmap.ErrInvalidConfig, layout)
+		}
+		cfg.layout = layout
+		return nil
+	}
+}
+
+// WithHasher sets the function hashing keys. It defaults to key.HashOf.
+func WithHasher(h Hasher) Option {
+	return func(cfg *config) error {
+		if h == nil {
+			return fmt.Errorf("%w: hasher is nil", ccmap.ErrInvalidConfig)
+		}
+		cfg.hasher = h
+		return nil
+	}
+}
+
+// WithSeed fixes the seed mixed into the hash of every key, which is
+// random by default. Fix it only to make tests reproducible, a known seed
+// lets colliding keys be crafted.
+func WithSeed(seed uint64) Option {
+	return func(cfg *config) error {
+		cfg.seed = seed
+		return nil
+	}
+}
+
+// WithRehashMode selects who moves entries while a segment rehashes.
+// It defaults to RehashInline.
+func WithRehashMode(mode RehashMode) Option {
+	return func(cfg *config) error {
+		if mode != RehashInline && mode != RehashBackground {
+			return fmt.Errorf("%w: unknown rehash mode %d", ccmap.ErrInvalidConfig, mode)
+		}
+		cfg.rehashMode = mode
+		return nil
+	}
+}
+
+// WithRehashBudget sets how long a background worker may hold the lock
+// of a segment in each step. It defaults to REHASH_BUDGET_DEFAULT.
+func WithRehashBudget(d time.Duration) Option {
+	return func(cfg *config) error {
+		if d <= 0 {
+			return fmt.Errorf("%w: rehash budget %s must be positive", ccmap.ErrInvalidConfig, d)
+		}
+		cfg.rehashBudget = d
+		return nil
+	}
+}
+
+// WithClock sets the clock telling when entries put by PutWithTTL expire.
+// It defaults to the system clock.
+func WithClock(clock Clock) Option {
+	return func(cfg *config) error {
+		if clock == nil {
+			return fmt.Errorf("%w: clock is nil", ccmap.ErrInvalidConfig)
+		}
+		cfg.clock = clock
+		return nil
+	}
+}
+
+// WithOnExpire sets a function called with the key and value of every
+// expired entry that gets deleted. It is called while the segment of the
+// key is locked, so it must not access the map.
+func WithOnExpire(f func(k Key, val interface{})) Option {
+	return func(cfg *config) error {
+		if f == nil {
+			return fmt.Errorf("%w: expire callback is nil", ccmap.ErrInvalidConfig)
+		}
+		cfg.onExpire = f
+		return nil
+	}
+}
+
+// WithJanitor starts a goroutine that deletes expired entries every
+// interval, visiting JANITOR_BUCKETS buckets of every segment at a time.
+// Without it expired entries are only deleted when they are found. The
+// map must be closed to stop the goroutine.
+func WithJanitor(interval time.Duration) Option {
+	return func(cfg *config) error {
+		if interval <= 0 {
+			return fmt.Errorf("%w: janitor interval %s must be positive", ccmap.ErrInvalidConfig, interval)
+		}
+		cfg.janitorInterval = interval
+		return nil
+	}
+}
+
+// WithMaxEntries bounds the map to about n entries. n is split evenly
+// between the segments, rounded up, and a segment evicts an entry picked
+// by the eviction policy when a new key would exceed its share. With
+// n = 100 and 16 segments, every segment holds at most 7 entries, 112 in
+// total, but keys are never spread perfectly evenly: the segment getting
+// an 8th key evicts while the map may hold far fewer than 100 entries.
+// n must be at least the number of segments, see WithSegments, fewer
+// segments suit a small n better. Get on a bounded map takes the lock of
+// the segment to record the access. By default maps are unbounded.
+func WithMaxEntries(n int) Option {
+	return func(cfg *config) error {
+		if n <= 0 {
+			return fmt.Errorf("%w: max entries %d must be positive", ccmap.ErrInvalidCapacity, n)
+		}
+		cfg.maxEntries = n
+		return nil
+	}
+}
+
+// WithEvictionListener sets a function called with the key and value of
+// every entry the map removes by itself and the reason why. It is called
+// while the segment of the key is locked, so it must not access the map.
+func WithEvictionListener(f func(k Key, val interface{}, reason EvictionReason)) Option {
+	return func(cfg *config) error {
+		if f == nil {
+			return fmt.Errorf("%w: eviction listener is nil", ccmap.ErrInvalidConfig)
+		}
+		cfg.onEvict = f
+		return nil
+	}
+}
+
+// WithEvictionPolicy selects which entries a bounded map evicts. It
+// defaults to PolicyLRU and only applies along with WithMaxEntries.
+func WithEvictionPolicy(p EvictionPolicy) Option {
+	return func(cfg *config) error {
+		if p != PolicyLRU && p != PolicyTinyLFU {
+			return fmt.Errorf("%w: unknown eviction policy %d", ccmap.ErrInvalidConfig, p)
+		}
+		cfg.policy = p
+		return nil
+	}
+}
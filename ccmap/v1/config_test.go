@@ -0,0 +1,119 @@
+package v1
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+// mustConfig returns the settings made of opts.
+func mustConfig(t *testing.T, opts ...Option) config {
+	cfg, err := newConfig(opts...)
+	assert.NoError(t, err)
+	return cfg
+}
+
+func TestNewDefaults(t *testing.T) {
+	m, err := New()
+	assert.NoError(t, err)
+
+	c := m.(*concurrentHashMap)
+	assert.Len(t, c.load().segments, SEGMENTS_DEFAULT)
+	for _, s := range c.load().segments {
+		assert.Equal(t, 16, s.tables[0].len())
+		assert.Equal(t, MAX_LOAD_FACTOR_DEFAULT, s.maxLoadFactor)
+		assert.Equal(t, MIN_LOAD_FACTOR_DEFAULT, s.minLoadFactor)
+		assert.Equal(t, GROWTH_FACTOR_DEFAULT, s.growthFactor)
+		assert.Nil(t, s.worker)
+	}
+}
+
+func TestNewInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+		err  error
+	}{
+		{"negative capacity", WithInitialCapacity(-1), ccmap.ErrInvalidCapacity},
+		{"huge capacity", WithInitialCapacity(math.MaxInt), ccmap.ErrCapacityExceeded},
+		{"no segments", WithSegments(0), ccmap.ErrInvalidConfig},
+		{"too many segments", WithSegments(MAX_SEGMENTS * 2), ccmap.ErrInvalidConfig},
+		{"segments not power of two", WithSegments(6), ccmap.ErrInvalidConfig},
+		{"zero max load factor", WithMaxLoadFactor(0), ccmap.ErrInvalidConfig},
+		{"negative min load factor", WithMinLoadFactor(-0.1), ccmap.ErrInvalidConfig},
+		{"growth factor 1", WithGrowthFactor(1), ccmap.ErrInvalidConfig},
+		{"growth factor not power of two", WithGrowthFactor(3), ccmap.ErrInvalidConfig},
+		{"nil hasher", WithHasher(nil), ccmap.ErrInvalidConfig},
+		{"unknown rehash mode", WithRehashMode(RehashMode(7)), ccmap.ErrInvalidConfig},
+		{"zero rehash budget", WithRehashBudget(0), ccmap.ErrInvalidConfig},
+		{"nil clock", WithClock(nil), ccmap.ErrInvalidConfig},
+		{"nil expire callback", WithOnExpire(nil), ccmap.ErrInvalidConfig},
+		{"zero janitor interval", WithJanitor(0), ccmap.ErrInvalidConfig},
+		{"zero max entries", WithMaxEntries(0), ccmap.ErrInvalidCapacity},
+		{"nil eviction listener", WithEvictionListener(nil), ccmap.ErrInvalidConfig},
+		{"unknown eviction policy", WithEvictionPolicy(EvictionPolicy(7)), ccmap.ErrInvalidConfig},
+	}
+
+	for _, tt := range tests {
+		m, err := New(tt.opt)
+		assert.ErrorIs(t, err, tt.err, tt.name)
+		assert.Nil(t, m, tt.name)
+	}
+}
+
+func TestNewLoadFactorsOverlap(t *testing.T) {
+	// 0.5 entries per bucket after growing would shrink at once.
+	_, err := New(WithMaxLoadFactor(1), WithMinLoadFactor(0.5))
+	assert.ErrorIs(t, err, ccmap.ErrInvalidConfig)
+
+	_, err = New(WithMaxLoadFactor(4), WithMinLoadFactor(0.5))
+	assert.NoError(t, err)
+}
+
+func TestNewInitialCapacity(t *testing.T) {
+	m, err := New(WithSegments(4), WithInitialCapacity(1000), WithMaxLoadFactor(0.5))
+	assert.NoError(t, err)
+
+	// 250 entries per segment at a load of 0.5, rounded up.
+	c := m.(*concurrentHashMap)
+	for _, s := range c.load().segments {
+		assert.Equal(t, 512, s.tables[0].len())
+	}
+
+	// leave room for the segments to be unevenly filled.
+	for i := 0; i < 800; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+	assert.Equal(t, int64(0), m.Stats().Rehashes)
+}
+
+func TestGrowthAndLoadFactor(t *testing.T) {
+	h, err := newHashMapConfig(16, mustConfig(t, WithMaxLoadFactor(2), WithGrowthFactor(4)))
+	assert.NoError(t, err)
+
+	fill(h, 32)
+	assert.False(t, h.isRehashing())
+
+	fill(h, 33)
+	assert.True(t, h.isRehashing())
+	assert.Equal(t, 64, h.tables[1].len())
+}
+
+func TestWithHasher(t *testing.T) {
+	calls := 0
+	m, err := New(WithSegments(1), WithHasher(func(k Key, seed uint64) int {
+		calls++
+		return HashOf(k, seed)
+	}))
+	assert.NoError(t, err)
+
+	m.Put(NewStringKey("k1"), 1)
+	actual, ok := m.Get(NewStringKey("k1"))
+	assert.True(t, ok)
+	assert.Equal(t, 1, actual)
+	assert.True(t, calls > 0)
+}
@@ -6,6 +6,9 @@ import (
 	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
 )
 
+// Entry is a <key, value> pair. An Entry stored in a map is read without
+// locking, so it must not be modified, SetKey and SetValue are only meant
+// for entries that are not stored yet.
 type Entry interface {
 	Key() Key
 	Value() interface{}
@@ -24,10 +27,23 @@ func newEntry(k Key, v interface{}) Entry {
 	}
 }
 
+// NewEntry creates an entry of k and v, e.g. to be stored by PutAll.
+func NewEntry(k Key, v interface{}) Entry {
+	return newEntry(k, v)
+}
+
 // entry is basic implementation of Entry.
 type entry struct {
 	k Key
 	v interface{}
+	// deadline is when the entry expires in Unix nanoseconds, 0 if never.
+	deadline int64
+	// prev and next link the entry into the list of the eviction policy
+	// of a bounded map. Unlike the other fields they change while the
+	// entry is stored, under the write lock, so Get must not read them.
+	// So does queue, the list of a tinyLFUPolicy the entry is in.
+	prev, next *entry
+	queue      uint8
 }
 
 // Key returns the key.
@@ -56,6 +72,7 @@ func (e *entry) String() string {
 }
 
 // linkedEntry inplements Entry and links to next entry.
+// Neither the entry nor next change once it is linked into a bucket.
 type linkedEntry struct {
 	Entry
 	next *linkedEntry
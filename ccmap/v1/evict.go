@@ -0,0 +1,164 @@
+package v1
+
+import (
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
+
+// EvictionReason tells why a map removed an entry by itself.
+type EvictionReason int
+
+const (
+	// ReasonCapacity means the segment of the entry was full, see
+	// WithMaxEntries.
+	ReasonCapacity EvictionReason = iota
+	// ReasonExpired means the entry expired, see PutWithTTL.
+	ReasonExpired
+)
+
+// String returns the name of r.
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonExpired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+// evictionPolicy picks the entries a bounded segment evicts. The segment
+// calls it for every entry it adds, finds with Get, replaces or deletes,
+// always under its write lock.
+type evictionPolicy interface {
+	// add records a new entry and returns the entry to evict if the
+	// segment is over its capacity, nil otherwise.
+	add(e *entry) *entry
+	// access records that e was read.
+	access(e *entry)
+	// replace records that old was replaced by new for the same key.
+	replace(old, new *entry)
+	// remove forgets e, it was deleted.
+	remove(e *entry)
+	// reset forgets all the entries.
+	reset()
+}
+
+// lruList is a doubly linked list of entries threaded through their prev
+// and next fields, the most recently used first.
+type lruList struct {
+	root entry
+	len  int
+}
+
+// init empties l.
+func (l *lruList) init() {
+	l.root.prev = &l.root
+	l.root.next = &l.root
+	l.len = 0
+}
+
+// pushFront inserts e at the front of l.
+func (l *lruList) pushFront(e *entry) {
+	e.prev = &l.root
+	e.next = l.root.next
+	e.prev.next = e
+	e.next.prev = e
+	l.len++
+}
+
+// remove removes e from l, e must be in l.
+func (l *lruList) remove(e *entry) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev = nil
+	e.next = nil
+	l.len--
+}
+
+// moveToFront moves e, which must be in l, to the front of l.
+func (l *lruList) moveToFront(e *entry) {
+	if l.root.next == e {
+		return
+	}
+	l.remove(e)
+	l.pushFront(e)
+}
+
+// replace puts new in the place of old, which must be in l.
+func (l *lruList) replace(old, new *entry) {
+	new.prev = old.prev
+	new.next = old.next
+	new.prev.next = new
+	new.next.prev = new
+	old.prev = nil
+	old.next = nil
+}
+
+// back returns the least recently used entry, or nil if l is empty.
+func (l *lruList) back() *entry {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.prev
+}
+
+// lruPolicy evicts the least recently used entry.
+type lruPolicy struct {
+	capacity int
+	list     lruList
+}
+
+// newLRUPolicy creates a lruPolicy keeping at most capacity entries.
+func newLRUPolicy(capacity int) *lruPolicy {
+	p := &lruPolicy{capacity: capacity}
+	p.list.init()
+	return p
+}
+
+func (p *lruPolicy) add(e *entry) *entry {
+	p.list.pushFront(e)
+	if p.list.len > p.capacity {
+		return p.list.back()
+	}
+	return nil
+}
+
+func (p *lruPolicy) access(e *entry) {
+	p.list.moveToFront(e)
+}
+
+func (p *lruPolicy) replace(old, new *entry) {
+	p.list.remove(old)
+	p.list.pushFront(new)
+}
+
+func (p *lruPolicy) remove(e *entry) {
+	p.list.remove(e)
+}
+
+func (p *lruPolicy) reset() {
+	p.list.init()
+}
+
+// access finds the entry of key like lookup and records the access to
+// the eviction policy, under the write lock.
+func (h *hashMap) access(key Key) (Entry, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	en, ok := h.lookup(key)
+	if ok {
+		h.policy.access(en.(*entry))
+	}
+	return en, ok
+}
+
+// evict deletes e, which the eviction policy picked.
+// The caller must hold the write lock.
+func (h *hashMap) evict(e *entry) {
+	h.unlink(e.k)
+	h.counters.evictions.Add(1)
+	if h.onEvict != nil {
+		h.onEvict(e.k, e.v, ReasonCapacity)
+	}
+}
@@ -0,0 +1,216 @@
+package v1
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+// eviction is an entry reported to an eviction listener.
+type eviction struct {
+	key    string
+	val    interface{}
+	reason EvictionReason
+}
+
+// evictions records the entries reported to an eviction listener.
+type evictions struct {
+	mu   sync.Mutex
+	list []eviction
+}
+
+func (e *evictions) onEvict(k Key, val interface{}, reason EvictionReason) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list = append(e.list, eviction{k.String(), val, reason})
+}
+
+// checkPolicy checks that the list of the LRU policy of every segment of
+// m holds the entries of the segment.
+func checkPolicy(t *testing.T, m ConcurrentMap) {
+	for _, s := range m.(*concurrentHashMap).load().segments {
+		s.mutex.RLock()
+		l := &s.policy.(*lruPolicy).list
+		assert.Equal(t, s.Len(), l.len)
+		for e := l.root.next; e != &l.root; e = e.next {
+			en, ok := s.find(e.k)
+			assert.True(t, ok)
+			assert.True(t, Entry(e) == en)
+		}
+		s.mutex.RUnlock()
+	}
+}
+
+func TestLRUList(t *testing.T) {
+	var l lruList
+	l.init()
+	assert.Nil(t, l.back())
+
+	e1, e2, e3 := &entry{v: 1}, &entry{v: 2}, &entry{v: 3}
+	l.pushFront(e1)
+	l.pushFront(e2)
+	l.pushFront(e3)
+	assert.Equal(t, 3, l.len)
+	assert.True(t, e1 == l.back())
+
+	l.moveToFront(e1)
+	assert.True(t, e2 == l.back())
+
+	l.remove(e2)
+	assert.True(t, e3 == l.back())
+	assert.Nil(t, e2.prev)
+	assert.Equal(t, 2, l.len)
+}
+
+func TestMaxEntriesEvictsLRU(t *testing.T) {
+	for _, layout := range []TableLayout{LayoutChained, LayoutOpenAddressing} {
+		var ev evictions
+		c, err := New(WithSegments(1), WithMaxEntries(3), WithTableLayout(layout),
+			WithEvictionListener(ev.onEvict))
+		assert.NoError(t, err)
+
+		c.Put(NewStringKey("k1"), 1)
+		c.Put(NewStringKey("k2"), 2)
+		c.Put(NewStringKey("k3"), 3)
+		c.Get(NewStringKey("k1"))
+		// replacing the value of a key does not evict.
+		c.Put(NewStringKey("k2"), 20)
+		assert.Empty(t, ev.list)
+
+		c.Put(NewStringKey("k4"), 4)
+		assert.Equal(t, []eviction{{NewStringKey("k3").String(), 3, ReasonCapacity}}, ev.list)
+		assert.Equal(t, 3, c.Len())
+		_, ok := c.Get(NewStringKey("k3"))
+		assert.False(t, ok)
+
+		c.Put(NewStringKey("k5"), 5)
+		assert.Equal(t, NewStringKey("k1").String(), ev.list[1].key)
+
+		// a deleted entry frees its place.
+		c.Delete(NewStringKey("k2"))
+		c.Put(NewStringKey("k6"), 6)
+		assert.Len(t, ev.list, 2)
+
+		st := c.Stats()
+		assert.Equal(t, int64(2), st.Evictions)
+		assert.Equal(t, int64(1), st.GetHits)
+		assert.Equal(t, int64(1), st.GetMisses)
+		checkPolicy(t, c)
+	}
+}
+
+func TestMaxEntriesPerSegment(t *testing.T) {
+	c, _ := New(WithSegments(4), WithMaxEntries(10))
+	for i := 0; i < 1000; i++ {
+		c.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+
+	// every segment holds 10 / 4 entries, rounded up.
+	assert.Equal(t, 12, c.Len())
+	for _, s := range c.(*concurrentHashMap).load().segments {
+		assert.Equal(t, 3, s.Len())
+	}
+	assert.Equal(t, int64(1000-12), c.Stats().Evictions)
+	checkPolicy(t, c)
+}
+
+func TestMaxEntriesBelowSegments(t *testing.T) {
+	_, err := New(WithMaxEntries(SEGMENTS_DEFAULT - 1))
+	assert.ErrorIs(t, err, ccmap.ErrInvalidConfig)
+
+	m, err := New(WithSegments(8), WithMaxEntries(8), WithSeed(1))
+	assert.NoError(t, err)
+	fillMap(m, 100)
+	for _, s := range m.(*concurrentHashMap).load().segments {
+		assert.Equal(t, 1, s.Len())
+	}
+}
+
+func TestMaxEntriesExpired(t *testing.T) {
+	clock := newFakeClock()
+	var ev evictions
+	c, _ := New(WithSegments(1), WithMaxEntries(2), WithClock(clock),
+		WithEvictionListener(ev.onEvict))
+
+	c.PutWithTTL(NewStringKey("k1"), 1, time.Minute)
+	c.Put(NewStringKey("k2"), 2)
+	clock.Advance(time.Minute)
+
+	_, ok := c.Get(NewStringKey("k1"))
+	assert.False(t, ok)
+	c.Put(NewStringKey("k3"), 3)
+
+	assert.Equal(t, []eviction{{NewStringKey("k1").String(), 1, ReasonExpired}}, ev.list)
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, int64(0), c.Stats().Evictions)
+	checkPolicy(t, c)
+}
+
+func TestMaxEntriesClear(t *testing.T) {
+	c, _ := New(WithSegments(1), WithMaxEntries(2))
+	fillMap(c, 5)
+
+	c.Clear()
+	checkPolicy(t, c)
+	fillMap(c, 5)
+	assert.Equal(t, 2, c.Len())
+	checkPolicy(t, c)
+}
+
+// TestMaxEntriesConcurrent runs writers and readers on a bounded map that
+// keeps rehashing and evicting, run it with -race.
+func TestMaxEntriesConcurrent(t *testing.T) {
+	c, _ := New(WithSegments(4), WithMaxEntries(400), WithInitialCapacity(16))
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				k := NewStringKey(fmt.Sprintf("%d", (i*7+w)%1000))
+				switch i % 4 {
+				case 0, 1:
+					c.Put(k, i)
+				case 2:
+					c.Get(k)
+				case 3:
+					c.Delete(k)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	assert.True(t, c.Len() <= 400)
+	checkPolicy(t, c)
+}
+
+func TestHitRatio(t *testing.T) {
+	c, _ := New()
+	assert.Equal(t, 0.0, c.Stats().HitRatio())
+
+	c.Put(NewStringKey("k1"), 1)
+	c.Get(NewStringKey("k1"))
+	c.Get(NewStringKey("k1"))
+	c.Get(NewStringKey("k1"))
+	c.Get(NewStringKey("k2"))
+	assert.Equal(t, 0.75, c.Stats().HitRatio())
+}
+
+// fillMap puts n keys into m.
+func fillMap(m ConcurrentMap, n int) {
+	for i := 0; i < n; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+}
+
+func BenchmarkCCHashMapGetBounded(b *testing.B) {
+	m, _ := New(WithMaxEntries(len(benchmarkKeys)))
+	benchmarkReadHeavy(b, m)
+}
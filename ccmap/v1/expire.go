@@ -0,0 +1,201 @@
+package v1
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
+
+const (
+	// JANITOR_BUCKETS is the number of buckets of every segment the
+	// janitor visits at a time, see WithJanitor.
+	JANITOR_BUCKETS = 64
+)
+
+// Clock tells the time to a map, see WithClock.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock of time.Now.
+type systemClock struct{}
+
+// Now returns the current time.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// PutWithTTL puts <key, val> like Put, but the entry expires ttl after
+// now, as told by the clock of h. An expired entry is reported absent at
+// once, but it is only deleted, and stops being counted by Len, when an
+// operation finds it or the janitor visits it, see WithJanitor.
+// Entries stored by Put and the other writes of a whole entry never
+// expire, while those updating the value of a present key, like Replace,
+// CompareAndSwap and Compute, keep its deadline. ttl must be positive,
+// otherwise val is not stored and false is returned. A ttl too long to
+// be represented never ends.
+func (h *hashMap) PutWithTTL(key Key, val interface{}, ttl time.Duration) bool {
+	if ttl <= 0 {
+		return false
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.store(&entry{k: key, v: val, deadline: h.deadlineAfter(ttl)}) == nil
+}
+
+// now returns the time of the clock of h in Unix nanoseconds.
+func (h *hashMap) now() int64 {
+	return h.clock.Now().UnixNano()
+}
+
+// deadlineAfter returns the deadline ttl after now, math.MaxInt64 if it
+// overflows. ttl must be positive.
+func (h *hashMap) deadlineAfter(ttl time.Duration) int64 {
+	now := h.now()
+	if now > 0 && int64(ttl) > math.MaxInt64-now {
+		return math.MaxInt64
+	}
+	return now + int64(ttl)
+}
+
+// update replaces the value of en, the entry of its key, keeping its
+// deadline. The caller must hold the write lock.
+func (h *hashMap) update(en Entry, val interface{}) bool {
+	return h.store(&entry{k: en.Key(), v: val, deadline: deadlineOf(en)}) == nil
+}
+
+// deadlineOf returns when en expires in Unix nanoseconds, 0 if never.
+func deadlineOf(en Entry) int64 {
+	if e, ok := en.(*entry); ok {
+		return e.deadline
+	}
+	return 0
+}
+
+// expired returns true if en has expired. The clock is only read if en
+// expires at all.
+func (h *hashMap) expired(en Entry) bool {
+	d := deadlineOf(en)
+	return d != 0 && d <= h.now()
+}
+
+// expiredAt returns true if en has expired at now.
+func expiredAt(en Entry, now int64) bool {
+	d := deadlineOf(en)
+	return d != 0 && d <= now
+}
+
+// expire deletes the expired entry en.
+// The caller must hold the write lock.
+func (h *hashMap) expire(en Entry) {
+	h.unlink(en.Key())
+	h.notifyExpired(en)
+}
+
+// notifyExpired records that en expired and was deleted.
+// The caller must hold the write lock.
+func (h *hashMap) notifyExpired(en Entry) {
+	h.counters.expirations.Add(1)
+	if h.onExpire != nil {
+		h.onExpire(en.Key(), en.Value())
+	}
+	if h.onEvict != nil {
+		h.onEvict(en.Key(), en.Value(), ReasonExpired)
+	}
+}
+
+// purge deletes the entry of key if it has expired.
+func (h *hashMap) purge(key Key) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.lookup(key)
+}
+
+// expireStep deletes the expired entries of the next n buckets, starting
+// where the previous step stopped: the buckets of tables[0] first, then
+// those of tables[1] while rehashing. A step stops early at the end of a
+// pass. Entries moved behind the cursor by a rehash are left to the next
+// pass.
+func (h *hashMap) expireStep(n int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := h.now()
+	for ; n > 0; n-- {
+		t, i := h.tables[0], h.sweepIdx
+		if i >= t.numBuckets() && h.isRehashing() {
+			i -= t.numBuckets()
+			t = h.tables[1]
+		}
+		if i >= t.numBuckets() {
+			h.sweepIdx = 0
+			return
+		}
+
+		h.sweepIdx++
+		for _, en := range t.bucketEntries(i) {
+			if expiredAt(en, now) {
+				h.expire(en)
+			}
+		}
+	}
+}
+
+// PutWithTTL puts <key, val> with a time to live, see hashMap.PutWithTTL.
+func (c *concurrentHashMap) PutWithTTL(key Key, val interface{}, ttl time.Duration) bool {
+	return c.segmentOf(key).PutWithTTL(key, val, ttl)
+}
+
+// expireStep runs hashMap.expireStep on every segment.
+func (c *concurrentHashMap) expireStep(n int) {
+	for _, s := range c.load().segments {
+		s.expireStep(n)
+	}
+}
+
+// janitor deletes the expired entries of a concurrentHashMap in
+// background.
+type janitor struct {
+	// done is closed to stop the janitor.
+	done    chan struct{}
+	stopped sync.WaitGroup
+	once    sync.Once
+}
+
+// startJanitor starts the janitor of c, visiting the segments every
+// interval.
+func (c *concurrentHashMap) startJanitor(interval time.Duration) {
+	j := &janitor{
+		done: make(chan struct{}),
+	}
+	c.janitor = j
+
+	j.stopped.Add(1)
+	go func() {
+		defer j.stopped.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-j.done:
+				return
+			case <-ticker.C:
+				c.expireStep(JANITOR_BUCKETS)
+			}
+		}
+	}()
+}
+
+// stop stops the janitor and waits for it to return. It may be called
+// more than once.
+func (j *janitor) stop() {
+	j.once.Do(func() {
+		close(j.done)
+	})
+	j.stopped.Wait()
+}
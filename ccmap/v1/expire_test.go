@@ -0,0 +1,269 @@
+package v1
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a Clock that only moves when told to.
+type fakeClock struct {
+	now atomic.Int64
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{}
+	c.now.Store(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	return time.Unix(0, c.now.Load())
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now.Add(int64(d))
+}
+
+// expired records the entries reported by OnExpire.
+type expired struct {
+	mu      sync.Mutex
+	entries map[string]interface{}
+}
+
+func (e *expired) onExpire(k Key, val interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.entries == nil {
+		e.entries = make(map[string]interface{})
+	}
+	e.entries[k.String()] = val
+}
+
+func (e *expired) len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.entries)
+}
+
+func newExpiringMap(t *testing.T, clock Clock, opts ...Option) ConcurrentMap {
+	m, err := New(append([]Option{WithSegments(4), WithClock(clock)}, opts...)...)
+	assert.NoError(t, err)
+	return m
+}
+
+func TestPutWithTTL(t *testing.T) {
+	clock := newFakeClock()
+	var exp expired
+	m := newExpiringMap(t, clock, WithOnExpire(exp.onExpire))
+
+	assert.True(t, m.PutWithTTL(NewStringKey("k1"), 1, time.Minute))
+	m.Put(NewStringKey("k2"), 2)
+
+	clock.Advance(59 * time.Second)
+	actual, ok := m.Get(NewStringKey("k1"))
+	assert.True(t, ok)
+	assert.Equal(t, 1, actual)
+
+	clock.Advance(time.Second)
+	_, ok = m.Get(NewStringKey("k1"))
+	assert.False(t, ok)
+	actual, ok = m.Get(NewStringKey("k2"))
+	assert.True(t, ok)
+	assert.Equal(t, 2, actual)
+
+	// Get deleted the expired entry.
+	assert.Equal(t, 1, m.Len())
+	assert.Equal(t, map[string]interface{}{NewStringKey("k1").String(): 1}, exp.entries)
+	assert.Equal(t, int64(1), m.Stats().Expirations)
+}
+
+func TestPutWithTTLInvalid(t *testing.T) {
+	m := newExpiringMap(t, newFakeClock())
+
+	assert.False(t, m.PutWithTTL(NewStringKey("k1"), 1, 0))
+	assert.False(t, m.PutWithTTL(NewStringKey("k1"), 1, -time.Second))
+	assert.True(t, m.IsEmpty())
+}
+
+func TestPutWithTTLSaturates(t *testing.T) {
+	clock := newFakeClock()
+	m := newExpiringMap(t, clock)
+	k := NewStringKey("k1")
+
+	assert.True(t, m.PutWithTTL(k, 1, math.MaxInt64))
+	en, ok := m.(*concurrentHashMap).segmentOf(k).find(k)
+	assert.True(t, ok)
+	assert.Equal(t, int64(math.MaxInt64), deadlineOf(en))
+
+	clock.Advance(100 * 365 * 24 * time.Hour)
+	actual, ok := m.Get(k)
+	assert.True(t, ok)
+	assert.Equal(t, 1, actual)
+}
+
+func TestReplaceKeepsTTL(t *testing.T) {
+	clock := newFakeClock()
+	m := newExpiringMap(t, clock)
+	k := NewStringKey("k1")
+	m.PutWithTTL(k, 1, time.Minute)
+
+	prev, ok := m.Replace(k, 2)
+	assert.True(t, ok)
+	assert.Equal(t, 1, prev)
+	actual, _ := m.Get(k)
+	assert.Equal(t, 2, actual)
+
+	clock.Advance(time.Minute)
+	_, ok = m.Get(k)
+	assert.False(t, ok)
+}
+
+func TestCompareAndSwapKeepsTTL(t *testing.T) {
+	clock := newFakeClock()
+	m := newExpiringMap(t, clock)
+	k := NewStringKey("k1")
+	m.PutWithTTL(k, 1, time.Minute)
+
+	assert.True(t, m.CompareAndSwap(k, 1, 2))
+	actual, _ := m.Get(k)
+	assert.Equal(t, 2, actual)
+
+	clock.Advance(time.Minute)
+	assert.False(t, m.CompareAndSwap(k, 2, 3))
+	_, ok := m.Get(k)
+	assert.False(t, ok)
+}
+
+func TestPutClearsTTL(t *testing.T) {
+	clock := newFakeClock()
+	m := newExpiringMap(t, clock)
+
+	m.PutWithTTL(NewStringKey("k1"), 1, time.Minute)
+	m.Put(NewStringKey("k1"), 2)
+	m.Put(NewStringKey("k2"), 2)
+	m.PutWithTTL(NewStringKey("k2"), 3, time.Minute)
+
+	clock.Advance(time.Hour)
+	actual, ok := m.Get(NewStringKey("k1"))
+	assert.True(t, ok)
+	assert.Equal(t, 2, actual)
+	_, ok = m.Get(NewStringKey("k2"))
+	assert.False(t, ok)
+}
+
+func TestExpiredIsAbsent(t *testing.T) {
+	clock := newFakeClock()
+	var exp expired
+	m := newExpiringMap(t, clock, WithOnExpire(exp.onExpire))
+
+	for i := 0; i < 5; i++ {
+		m.PutWithTTL(NewStringKey(fmt.Sprintf("%d", i)), i, time.Minute)
+	}
+	m.Put(NewStringKey("kept"), 5)
+	clock.Advance(time.Minute)
+
+	keys := []Key{}
+	m.Range(func(k Key, val interface{}) bool {
+		keys = append(keys, k)
+		return true
+	})
+	assert.Equal(t, []Key{NewStringKey("kept")}, keys)
+
+	assert.False(t, m.Delete(NewStringKey("0")))
+	assert.True(t, m.PutIfAbsent(NewStringKey("1"), 10))
+	_, ok := m.Replace(NewStringKey("2"), 20)
+	assert.False(t, ok)
+	_, ok = m.LoadAndDelete(NewStringKey("3"))
+	assert.False(t, ok)
+	actual, ok := m.Compute(NewStringKey("4"), func(old interface{}, loaded bool) (interface{}, bool) {
+		assert.False(t, loaded)
+		return 40, true
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 40, actual)
+
+	assert.Equal(t, 5, exp.len())
+	assert.Equal(t, 3, m.Len())
+}
+
+func TestExpireStep(t *testing.T) {
+	clock := newFakeClock()
+	var exp expired
+	h, err := newHashMapConfig(64, mustConfig(t, WithClock(clock), WithOnExpire(exp.onExpire)))
+	assert.NoError(t, err)
+
+	for i := 0; i < 50; i++ {
+		h.PutWithTTL(NewStringKey(fmt.Sprintf("%d", i)), i, time.Minute)
+	}
+	h.Put(NewStringKey("kept"), 50)
+
+	h.expireStep(64)
+	assert.Equal(t, 51, h.Len())
+
+	clock.Advance(time.Minute)
+	h.expireStep(8)
+	assert.True(t, h.Len() > 1)
+	for i := 0; i < 64/8 && h.Len() > 1; i++ {
+		h.expireStep(8)
+	}
+	assert.Equal(t, 1, h.Len())
+	assert.Equal(t, 50, exp.len())
+
+	_, ok := h.Get(NewStringKey("kept"))
+	assert.True(t, ok)
+}
+
+func TestExpireStepWhileRehashing(t *testing.T) {
+	clock := newFakeClock()
+	h, err := newHashMapConfig(4, mustConfig(t, WithClock(clock), WithMinLoadFactor(0)))
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		h.PutWithTTL(NewStringKey(fmt.Sprintf("%d", i)), i, time.Minute)
+	}
+	clock.Advance(time.Minute)
+
+	for i := 0; i < 10 && h.Len() > 0; i++ {
+		h.expireStep(1024)
+	}
+	assert.True(t, h.IsEmpty())
+}
+
+func TestJanitor(t *testing.T) {
+	clock := newFakeClock()
+	m := newExpiringMap(t, clock, WithJanitor(time.Millisecond))
+	defer m.Close()
+
+	for i := 0; i < 1000; i++ {
+		m.PutWithTTL(NewStringKey(fmt.Sprintf("%d", i)), i, time.Minute)
+	}
+	clock.Advance(time.Minute)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !m.IsEmpty() {
+		if time.Now().After(deadline) {
+			t.Fatal("janitor did not delete the expired entries")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, int64(1000), m.Stats().Expirations)
+}
+
+func TestJanitorStopsOnClose(t *testing.T) {
+	m := newExpiringMap(t, newFakeClock(), WithJanitor(time.Millisecond))
+	m.Close()
+	m.Close()
+
+	select {
+	case <-m.(*concurrentHashMap).janitor.done:
+	default:
+		t.Fatal("janitor not stopped")
+	}
+}
@@ -1,7 +1,10 @@
 package v1
 
 import (
+	"fmt"
+	"runtime"
 	"sync"
+	"sync/atomic"
 
 	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
 
@@ -9,93 +12,157 @@ import (
 )
 
 // hashMap is the default implementation of Map.
+//
+// It grows incrementally: once it gets too full a second, bigger htable is
+// allocated and every following write moves one bucket of tables[0] into
+// tables[1]. Only writers, which hold the exclusive lock, move entries.
+// In RehashBackground mode the buckets are moved by a worker goroutine
+// instead of the writers, unless it falls behind, see overloaded.
+//
+// Get takes no lock at all: buckets are copied on write, see bucket, and
+// the pair of tables is published through readTables. Range and Stats
+// still take the shared lock to get a consistent view of the tables.
 type hashMap struct {
 	// -1: no rehash; otherwise it is rehashing
 	rehashIdx int
-	entryCnt  int
-	tables    []*htable
-	mutex     sync.RWMutex
+	// entryCnt is only updated under the write lock, but read without it.
+	entryCnt atomic.Int64
+	tables   []table
+	// readTables holds tables[0] and tables[1] for the lock-free readers.
+	// It is replaced, never modified, whenever tables changes.
+	readTables atomic.Pointer[[2]table]
+	mutex      sync.RWMutex
+	counters   counters
+	// hasher hashes the keys with seed mixed in.
+	hasher Hasher
+	seed   uint64
+	// minSize is the initial size of the table, h never shrinks below it.
+	minSize int
+	// maxSize is the size h never grows beyond.
+	maxSize int
+	// maxLoadFactor is the load factor above which h grows by growthFactor.
+	maxLoadFactor float64
+	growthFactor  int
+	// minLoadFactor is the load factor below which h shrinks.
+	minLoadFactor float64
+	// worker moves buckets in RehashBackground mode, nil otherwise.
+	worker *rehashWorker
+	// maxRehashLoad is the load factor of tables[1] above which writers
+	// stop leaving the rehash to worker.
+	maxRehashLoad float64
+	// newTable creates the tables of the layout of h.
+	newTable func(size int) (table, error)
+	// clock tells when entries expire, onExpire is called for each
+	// expired entry that gets deleted, if not nil.
+	clock    Clock
+	onExpire func(k Key, val interface{})
+	// sweepIdx is the next bucket expireStep visits.
+	sweepIdx int
+	// policy picks the entries to evict if h is bounded, nil otherwise.
+	// onEvict is called for each evicted entry, if not nil.
+	policy  evictionPolicy
+	onEvict func(k Key, val interface{}, reason EvictionReason)
 }
 
+// NewHashMap creates a hashMap of at least size buckets, size is rounded
+// up to a power of two. It fails with ccmap.ErrInvalidCapacity if size is
+// not positive and with ccmap.ErrCapacityExceeded if it is bigger than
+// ccmap.MAX_TABLE_SIZE.
 func NewHashMap(size int) (ccmap.Map, error) {
-	var err error
+	return newHashMap(size)
+}
 
-	tables := make([]*htable, 2)
-	tables[1] = nil
-	tables[0], err = newHtable(size)
+func newHashMap(size int) (*hashMap, error) {
+	cfg, err := newConfig()
 	if err != nil {
 		return nil, err
 	}
-
-	return &hashMap{
-		entryCnt:  0,
-		tables:    tables,
-		rehashIdx: -1,
-	}, nil
+	return newHashMapConfig(size, cfg)
 }
 
-func newHashMap(size int) (*hashMap, error) {
-	var err error
+// newHashMapConfig creates a hashMap of at least size buckets, cfg must
+// be valid.
+func newHashMapConfig(size int, cfg config) (*hashMap, error) {
+	size, err := tableSizeFor(size)
+	if err != nil {
+		return nil, err
+	}
 
-	tables := make([]*htable, 2)
+	newTable := cfg.layout.newTable
+	tables := make([]table, 2)
 	tables[1] = nil
-	tables[0], err = newHtable(size)
+	tables[0], err = newTable(size)
 	if err != nil {
 		return nil, err
 	}
 
-	return &hashMap{
-		entryCnt:  0,
+	h := &hashMap{
 		tables:    tables,
 		rehashIdx: -1,
-	}, nil
+		hasher:    cfg.hasher,
+		seed:      cfg.seed,
+
+		minSize:       tables[0].len(),
+		maxSize:       ccmap.MAX_TABLE_SIZE,
+		maxLoadFactor: cfg.maxLoadFactor,
+		growthFactor:  cfg.growthFactor,
+		minLoadFactor: cfg.minLoadFactor,
+		maxRehashLoad: cfg.maxRehashLoad(),
+		newTable:      newTable,
+		clock:         cfg.clock,
+		onExpire:      cfg.onExpire,
+		onEvict:       cfg.onEvict,
+	}
+	if cfg.maxEntries > 0 {
+		h.policy = cfg.policy.newPolicy(cfg.maxEntries)
+	}
+	h.counters.gets.init(runtime.GOMAXPROCS(0))
+
+	h.publish()
+
+	if cfg.rehashMode == RehashBackground {
+		h.startWorker(cfg.rehashBudget)
+	}
+	return h, nil
 }
 
 // Put puts <key, val> pair in correct slot.
-// It returns true if succeed; otherwise false.
+// It returns true if succeed; otherwise false, see ccmap.Map.
 func (h *hashMap) Put(key Key, val interface{}) bool {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
-	entry := newEntry(key, val)
-	if !h.isRehashing() {
-		if ok := h.putEntry(0, entry); !ok {
-			return false
-		}
-
-		if h.entryCnt > len(h.tables[0].buckets) {
-			h.beginRehash()
-		}
-
-		return true
-	}
+	return h.put(key, val)
+}
 
-	if ok := h.putEntry(1, entry); !ok {
-		return false
-	}
+// TryPut puts <key, val> like Put, but it returns why val could not be
+// stored: an error wrapping ccmap.ErrCapacityExceeded.
+func (h *hashMap) TryPut(key Key, val interface{}) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
 
-	h.rehash()
-	return true
+	return h.store(&entry{k: key, v: val})
 }
 
 // Get gets the value based on key.
 // If value exists, it returns value and TRUE;
 // otherwise it returns nil and FALSE.
+// Get takes no lock, it is safe to call it concurrently with any method.
+// If h is bounded, see WithMaxEntries, Get takes the write lock to record
+// the access.
 func (h *hashMap) Get(key Key) (interface{}, bool) {
-	h.mutex.RLock()
-	defer h.mutex.RUnlock()
-
-	if h.isRehashing() {
-		if en, ok := h.tables[1].get(key); ok {
-			h.rehash()
-			return en.Value(), true
-		}
+	var en Entry
+	var ok bool
+	if h.policy != nil {
+		en, ok = h.access(key)
+	} else {
+		en, ok = h.load(key)
 	}
-
-	if en, ok := h.tables[0].get(key); ok {
-		return en.Value(), true
+	h.counters.gets.record(ok)
+	if !ok {
+		return nil, false
 	}
-	return nil, false
+	return en.Value(), true
 }
 
 // Delete deletes value based on key.
@@ -104,36 +171,279 @@ func (h *hashMap) Delete(key Key) bool {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
+	_, ok := h.remove(key)
+	return ok
+}
+
+// find finds the entry of key in both tables, expired or not.
+// The caller must hold at least the shared lock.
+func (h *hashMap) find(key Key) (Entry, bool) {
+	hash := h.hashOf(key)
+	en, ok := h.tables[0].get(hash, key)
+	if !ok && h.isRehashing() {
+		en, ok = h.tables[1].get(hash, key)
+	}
+	return en, ok
+}
+
+// lookup finds the entry of key like find, but an expired entry is
+// deleted and reported absent. The caller must hold the write lock.
+func (h *hashMap) lookup(key Key) (Entry, bool) {
+	en, ok := h.find(key)
+	if ok && h.expired(en) {
+		h.expire(en)
+		return nil, false
+	}
+	return en, ok
+}
+
+// load finds the entry of key like lookup, but without locking.
+//
+// Entries only move from tables[0] to tables[1], and are linked into
+// tables[1] before being unlinked from tables[0], so searching tables[0]
+// first never misses a moving entry. If the pair of tables was replaced
+// during the search, the entry may have moved to a table the search did
+// not see, so it searches again.
+//
+// An expired entry is reported absent, and deleted under the write lock.
+func (h *hashMap) load(key Key) (Entry, bool) {
+	hash := h.hashOf(key)
+	tables := h.readTables.Load()
+	for {
+		en, ok := tables[0].get(hash, key)
+		if !ok && tables[1] != nil {
+			en, ok = tables[1].get(hash, key)
+		}
+		if ok {
+			if h.expired(en) {
+				h.purge(key)
+				return nil, false
+			}
+			return en, true
+		}
+
+		current := h.readTables.Load()
+		if current == tables {
+			return nil, false
+		}
+		tables = current
+	}
+}
+
+// publish publishes the current tables to the lock-free readers.
+// It must only be called while holding the write lock.
+func (h *hashMap) publish() {
+	h.readTables.Store(&[2]table{h.tables[0], h.tables[1]})
+}
+
+// hashOf returns the hash of key under the seed of h.
+func (h *hashMap) hashOf(key Key) int {
+	return h.hasher(key, h.seed)
+}
+
+// put puts <key, val> pair in correct slot.
+// The caller must hold the write lock.
+func (h *hashMap) put(key Key, val interface{}) bool {
+	return h.store(&entry{k: key, v: val}) == nil
+}
+
+// store puts en in correct slot. It returns an error wrapping
+// ccmap.ErrCapacityExceeded if en is for a new key and h cannot grow to
+// hold it, h is left unchanged then.
+// The caller must hold the write lock.
+func (h *hashMap) store(en *entry) error {
+	key := en.k
+	hash := h.hashOf(key)
+	h.step()
+
+	// while rehashing, a key that still lives in a bucket that has not
+	// been moved yet is replaced there instead of being duplicated in
+	// tables[1].
+	tableIdx := 0
+	if h.isRehashing() {
+		if _, found := h.tables[0].get(hash, key); !found {
+			tableIdx = 1
+		}
+	}
+
+	var old Entry
+	if h.policy != nil {
+		old, _ = h.find(key)
+	}
+
+	cnt := h.entryCnt.Load()
+	ok := h.putEntry(tableIdx, hash, en)
+	if !ok && tableIdx == 1 {
+		if err := h.regrow(); err != nil {
+			return err
+		}
+		ok = h.putEntry(tableIdx, hash, en)
+	}
+	if !ok {
+		return fmt.Errorf("%w: table of %d slots is full",
+			ccmap.ErrCapacityExceeded, h.tables[tableIdx].len())
+	}
+
+	if err := h.resize(); err != nil && h.entryCnt.Load() > cnt {
+		// h cannot grow, undo the add to keep it within its capacity.
+		h.tables[tableIdx].delete(hash, key)
+		h.entryCnt.Store(cnt)
+		return err
+	}
+
+	if h.policy != nil {
+		h.track(old, en)
+	}
+	return nil
+}
+
+// track tells the eviction policy that en was stored, replacing old if
+// not nil, and evicts the entry it picks.
+// The caller must hold the write lock.
+func (h *hashMap) track(old Entry, en *entry) {
+	if old != nil {
+		h.policy.replace(old.(*entry), en)
+		return
+	}
+	if victim := h.policy.add(en); victim != nil {
+		h.evict(victim)
+	}
+}
+
+// remove deletes the entry of key and returns it. An expired entry is
+// deleted too, but reported absent.
+// The caller must hold the write lock.
+func (h *hashMap) remove(key Key) (Entry, bool) {
+	en, ok := h.unlink(key)
+	if ok && h.expired(en) {
+		h.notifyExpired(en)
+		en, ok = nil, false
+	}
+
+	record(ok, &h.counters.deleteHits, &h.counters.deleteMisses)
+	return en, ok
+}
+
+// unlink deletes the entry of key from both tables and returns it.
+// The caller must hold the write lock.
+func (h *hashMap) unlink(key Key) (Entry, bool) {
+	hash := h.hashOf(key)
 	deleted := 0
-	_, cnt := h.tables[0].delete(key)
+	en, cnt := h.tables[0].delete(hash, key)
 	deleted += cnt
 
 	if h.isRehashing() {
-		_, cnt := h.tables[1].delete(key)
-		deleted += cnt
-		h.rehash()
+		if en2, cnt := h.tables[1].delete(hash, key); cnt > 0 {
+			en = en2
+			deleted += cnt
+		}
+		h.step()
 	}
 
-	h.entryCnt -= deleted
+	h.entryCnt.Add(-int64(deleted))
+	if deleted > 0 && h.policy != nil {
+		h.policy.remove(en.(*entry))
+	}
+	// shrinking is best effort, h stays valid if it fails.
+	h.resize()
+
+	return en, deleted > 0
+}
+
+// Range calls f for each key and value in h, see ccmap.Map.
+// The entries of both tables are copied under the shared lock and f is
+// called after it is released.
+func (h *hashMap) Range(f func(k Key, val interface{}) bool) {
+	for _, en := range h.snapshot() {
+		if !f(en.k, en.v) {
+			return
+		}
+	}
+}
+
+// snapshot copies all the <key, value> pairs of h that have not
+// expired.
+func (h *hashMap) snapshot() []entry {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
 
-	if deleted > 0 {
+	now := h.now()
+	entries := make([]entry, 0, h.Len())
+	collect := func(en Entry) bool {
+		if !expiredAt(en, now) {
+			entries = append(entries, entry{k: en.Key(), v: en.Value()})
+		}
 		return true
 	}
-	return false
+
+	h.tables[0].each(collect)
+	if h.isRehashing() {
+		h.tables[1].each(collect)
+	}
+	return entries
+}
+
+// Len returns number of entries, see ccmap.Map.
+func (h *hashMap) Len() int {
+	return int(h.entryCnt.Load())
+}
+
+// IsEmpty returns true if h has no entry.
+func (h *hashMap) IsEmpty() bool {
+	return h.Len() == 0
 }
 
-// Size returns number of entries.
+// Size returns number of entries, it is the same as Len.
 func (h *hashMap) Size() int {
-	return h.entryCnt
+	return h.Len()
 }
 
-// putEntry puts en into tables[tableIdx].
-// It returns true if succeeds, otherwise false.
-func (h *hashMap) putEntry(tableIdx int, en Entry) bool {
-	status := h.tables[tableIdx].put(en)
+// Stats returns a snapshot of the statistics of h. It walks every bucket
+// under the shared lock, so it costs O(buckets).
+func (h *hashMap) Stats() SegmentStats {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	t := h.tables[0]
+	chain := t.longestChain()
+	if h.isRehashing() {
+		t = h.tables[1]
+		if c := t.longestChain(); c > chain {
+			chain = c
+		}
+	}
 
-	if status == entryAdd {
-		h.entryCnt++
+	getHits, getMisses := h.counters.gets.load()
+	return SegmentStats{
+		Entries:      h.Len(),
+		Buckets:      t.len(),
+		LoadFactor:   float64(h.Len()) / float64(t.len()),
+		LongestChain: chain,
+		Rehashing:    h.isRehashing(),
+		Rehashes:     h.counters.rehashes.Load(),
+		Shrinks:      h.counters.shrinks.Load(),
+		PutHits:      h.counters.putHits.Load(),
+		PutMisses:    h.counters.putMisses.Load(),
+		GetHits:      getHits,
+		GetMisses:    getMisses,
+		DeleteHits:   h.counters.deleteHits.Load(),
+		DeleteMisses: h.counters.deleteMisses.Load(),
+		Expirations:  h.counters.expirations.Load(),
+		Evictions:    h.counters.evictions.Load(),
+	}
+}
+
+// putEntry puts en into tables[tableIdx], hash is the hash of its key.
+// It returns true if succeeds, otherwise false.
+func (h *hashMap) putEntry(tableIdx int, hash int, en Entry) bool {
+	status := h.tables[tableIdx].put(hash, en)
+
+	switch status {
+	case entryAdd:
+		h.entryCnt.Add(1)
+		h.counters.putMisses.Add(1)
+	case entryReplace:
+		h.counters.putHits.Add(1)
 	}
 
 	if status == entryErr {
@@ -143,18 +453,103 @@ func (h *hashMap) putEntry(tableIdx int, en Entry) bool {
 	return true
 }
 
-/*********** Expand Hash ***********/
+/*********** Expand and Shrink Hash ***********/
 
 func (h *hashMap) isRehashing() bool {
 	return h.rehashIdx != -1
 }
 
-// beginRehash sets rehashIdx to be 0, creates new htable for
-// tables[1].
-func (h *hashMap) beginRehash() {
+// resize begins to rehash h into a bigger table once its load factor
+// exceeds maxLoadFactor, or into a smaller one once it falls below
+// minLoadFactor. The tombstones of an open-addressing table count in its
+// load factor. It must only be called while holding the write lock.
+// It returns an error if h needs to grow but cannot.
+func (h *hashMap) resize() error {
+	if h.isRehashing() {
+		return nil
+	}
+
+	size, n := h.tables[0].len(), h.Len()
+	tombstones := h.tables[0].tombstones()
+	if float64(n+tombstones) > float64(size)*h.maxLoadFactor {
+		// tombstones fill the table like entries. Once they outnumber
+		// the entries so much that the table would be less than half
+		// full without them, rehashing it at the same size drops them.
+		if float64(n) <= float64(size)*h.maxLoadFactor/2 {
+			return h.beginRehash(size)
+		}
+		if size >= h.maxSize {
+			return fmt.Errorf("%w: %d entries in %d buckets",
+				ccmap.ErrCapacityExceeded, n, size)
+		}
+
+		// stop at maxSize instead of overflowing.
+		newSize := h.maxSize
+		if size <= h.maxSize/h.growthFactor {
+			newSize = size * h.growthFactor
+		}
+		return h.beginRehash(newSize)
+	}
+
+	if size > h.minSize && float64(n) < float64(size)*h.minLoadFactor {
+		// aim at half the max load factor after shrinking.
+		newSize := h.minSize
+		for float64(newSize)*h.maxLoadFactor < float64(n*2) {
+			newSize = newSize << 1
+		}
+		if newSize < size {
+			if err := h.beginRehash(newSize); err != nil {
+				return err
+			}
+			h.counters.shrinks.Add(1)
+		}
+	}
+	return nil
+}
+
+// beginRehash sets rehashIdx to be 0, creates new table of newSize for
+// tables[1]. h is left unchanged if the table cannot be created.
+func (h *hashMap) beginRehash(newSize int) error {
+	t, err := h.newTable(newSize)
+	if err != nil {
+		return err
+	}
+
 	h.rehashIdx = 0
-	newSize := len(h.tables[0].buckets) * 2
-	h.tables[1], _ = newHtable(newSize)
+	h.tables[1] = t
+	h.publish()
+	h.counters.rehashes.Add(1)
+
+	if h.worker != nil {
+		h.worker.wake()
+	}
+	return nil
+}
+
+// regrow replaces tables[1], an open-addressing table that filled up
+// before the rehash ended, by a bigger one holding its entries. The rehash
+// then goes on into the new table. tables[1] is left unchanged, so the
+// lock-free readers still find its entries until the new one is published.
+// It must only be called while holding the write lock and rehashing.
+func (h *hashMap) regrow() error {
+	size := h.tables[1].len()
+	if size >= h.maxSize {
+		return fmt.Errorf("%w: %d entries in %d buckets",
+			ccmap.ErrCapacityExceeded, h.Len(), size)
+	}
+	t, err := h.newTable(min(size*h.growthFactor, h.maxSize))
+	if err != nil {
+		return err
+	}
+
+	h.tables[1].each(func(en Entry) bool {
+		t.push(h.hashOf(en.Key()), en)
+		return true
+	})
+	h.tables[1] = t
+	h.publish()
+	h.counters.rehashes.Add(1)
+	return nil
 }
 
 // stopRehash switches old and new htable internally, resets
@@ -163,24 +558,107 @@ func (h *hashMap) stopRehash() {
 	h.tables[0] = h.tables[1]
 	h.tables[1] = nil
 	h.rehashIdx = -1
+	h.publish()
 }
 
-// rehash moves tables[0]'s entry to tables[1]
+// Clear deletes all the entries of h and resets its table to the initial
+// size, abandoning the rehash in progress if any. If the new table cannot
+// be created, the current tables are emptied and kept instead. The
+// statistics are kept.
+func (h *hashMap) Clear() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	t, err := h.newTable(h.minSize)
+	if err != nil {
+		h.empty()
+	} else {
+		h.tables[0] = t
+		h.tables[1] = nil
+		h.rehashIdx = -1
+		h.publish()
+	}
+	h.sweepIdx = 0
+	h.entryCnt.Store(0)
+	if h.policy != nil {
+		h.policy.reset()
+	}
+}
+
+// empty deletes the entries of both tables in place, ending the rehash in
+// progress if any. It must only be called while holding the write lock.
+func (h *hashMap) empty() {
+	for _, t := range h.tables {
+		if t == nil {
+			continue
+		}
+		for i := 0; i < t.numBuckets(); i++ {
+			t.clearBucket(i)
+		}
+	}
+	h.finishRehash()
+}
+
+// step moves buckets on behalf of a writer if h is rehashing: one in
+// RehashInline mode, all the remaining ones if the background worker fell
+// behind, none otherwise.
+// It must only be called while holding the write lock.
+func (h *hashMap) step() {
+	switch {
+	case !h.isRehashing():
+	case h.worker == nil:
+		h.rehash()
+	case h.overloaded():
+		h.finishRehash()
+	}
+}
+
+// finishRehash moves all the remaining buckets of tables[0].
+// It must only be called while holding the write lock.
+func (h *hashMap) finishRehash() {
+	for h.isRehashing() {
+		h.rehash()
+	}
+}
+
+// rehash moves the next non-empty bucket of tables[0] to tables[1],
+// whether tables[1] is bigger or smaller.
+// It must only be called while holding the write lock.
 func (h *hashMap) rehash() {
+	old := h.tables[0]
+	buckets := old.numBuckets()
+
 	// find the non-empty bucket
-	b := h.tables[0].buckets[h.rehashIdx]
-	for b.Size() == 0 && h.rehashIdx < len(h.tables[0].buckets) {
-		b = h.tables[0].buckets[h.rehashIdx]
+	for h.rehashIdx < buckets && old.bucketLen(h.rehashIdx) == 0 {
 		h.rehashIdx++
 	}
 
-	// move old entries
-	for en, ok := b.Pop(); ok; en, ok = b.Pop() {
-		h.tables[1].push(en)
+	// move old entries, linking them into tables[1] before unlinking
+	// them so that the lock-free readers always find them, see load.
+	if h.rehashIdx < buckets {
+		for _, en := range old.bucketEntries(h.rehashIdx) {
+			hash := h.hashOf(en.Key())
+			if h.tables[1].push(hash, en) {
+				continue
+			}
+			// tables[1] fills up if the background worker fell
+			// behind, it then grows. At the maximum size it has
+			// room for every entry, resize keeps them below its
+			// load factor, so a failure here is a bug that would
+			// lose the bucket.
+			if err := h.regrow(); err != nil {
+				panic("ccmap/v1: rehash cannot move an entry: " + err.Error())
+			}
+			if !h.tables[1].push(hash, en) {
+				panic("ccmap/v1: rehash cannot move an entry into a regrown table")
+			}
+		}
+		old.clearBucket(h.rehashIdx)
+		h.rehashIdx++
 	}
 
 	// rehash ends
-	if h.rehashIdx == len(h.tables[0].buckets) {
+	if h.rehashIdx == buckets {
 		h.stopRehash()
 	}
 }
@@ -1,10 +1,14 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
+	"runtime"
+	"sync"
 	"testing"
 
 	"github.com/csimplestring/go-concurrent-map/algo/random"
+	"github.com/csimplestring/go-concurrent-map/ccmap"
 	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
 	"github.com/stretchr/testify/assert"
 )
@@ -38,6 +42,76 @@ func TestHashMapSize(t *testing.T) {
 	assert.Equal(t, 2, m.Size())
 }
 
+func TestNewHashMapCapacity(t *testing.T) {
+	m, err := newHashMap(6)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, m.tables[0].len())
+
+	_, err = NewHashMap(0)
+	assert.ErrorIs(t, err, ccmap.ErrInvalidCapacity)
+
+	_, err = NewHashMap(ccmap.MAX_TABLE_SIZE + 1)
+	assert.ErrorIs(t, err, ccmap.ErrCapacityExceeded)
+}
+
+func TestHashMapPutCapacityExceeded(t *testing.T) {
+	m, _ := newHashMap(4)
+	m.maxSize = 8
+
+	fill(m, 8)
+	for m.isRehashing() {
+		m.Delete(NewStringKey("absent"))
+	}
+	assert.Equal(t, 8, m.tables[0].len())
+
+	// a new key does not fit any more, replacing still works.
+	assert.False(t, m.Put(NewStringKey("k1"), 1))
+	assert.Equal(t, 8, m.Size())
+	_, ok := m.Get(NewStringKey("k1"))
+	assert.False(t, ok)
+
+	assert.True(t, m.Put(NewStringKey("0"), 10))
+	actual, _ := m.Get(NewStringKey("0"))
+	assert.Equal(t, 10, actual)
+
+	// LoadOrStore reports the failed store.
+	actual, loaded := m.LoadOrStore(NewStringKey("k1"), 1)
+	assert.Nil(t, actual)
+	assert.False(t, loaded)
+
+	// once a key is deleted there is room again.
+	assert.True(t, m.Delete(NewStringKey("1")))
+	assert.True(t, m.Put(NewStringKey("k1"), 1))
+}
+
+func TestHashMapTryPut(t *testing.T) {
+	for _, layout := range []TableLayout{LayoutChained, LayoutOpenAddressing} {
+		m, _ := newHashMapConfig(8, mustConfig(t, WithTableLayout(layout)))
+		m.maxSize = 8
+
+		var err error
+		for i := 0; err == nil; i++ {
+			err = m.TryPut(NewStringKey(fmt.Sprintf("%d", i)), i)
+		}
+		assert.True(t, errors.Is(err, ccmap.ErrCapacityExceeded), "%v", err)
+		assert.NoError(t, m.TryPut(NewStringKey("0"), 10))
+		assert.False(t, m.Put(NewStringKey("k1"), 1))
+	}
+
+	c, _ := New()
+	assert.NoError(t, c.TryPut(NewStringKey("k1"), 1))
+	actual, _ := c.Get(NewStringKey("k1"))
+	assert.Equal(t, 1, actual)
+}
+
+func TestHashMapGrowthStopsAtMaxSize(t *testing.T) {
+	m, _ := newHashMapConfig(4, mustConfig(t, WithGrowthFactor(16), WithMinLoadFactor(0)))
+	m.maxSize = 32
+
+	fill(m, 5)
+	assert.Equal(t, 32, m.tables[1].len())
+}
+
 func TestHashMapPut(t *testing.T) {
 	m, _ := NewHashMap(100)
 
@@ -124,6 +198,400 @@ func TestHashMapDeleteFail(t *testing.T) {
 	}
 }
 
+// newRehashingMap returns a hashMap that has just started to rehash.
+func newRehashingMap(t *testing.T) *hashMap {
+	t.Helper()
+	m, err := newHashMap(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; !m.isRehashing(); i++ {
+		m.Put(NewStringKey(fmt.Sprintf("r%d", i)), i)
+	}
+	return m
+}
+
+func TestHashMapGetDoesNotRehash(t *testing.T) {
+	m := newRehashingMap(t)
+	idx := m.rehashIdx
+
+	for i := 0; i < 5; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("r%d", i)))
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+		actual, ok = m.Get(NewStringKey("absent"))
+		assert.False(t, ok)
+		assert.Nil(t, actual)
+	}
+
+	assert.True(t, m.isRehashing())
+	assert.Equal(t, idx, m.rehashIdx)
+}
+
+func TestHashMapPutWhileRehashing(t *testing.T) {
+	m := newRehashingMap(t)
+	size := m.Size()
+
+	// overwriting keys that may not have been moved yet must not
+	// create duplicates.
+	for i := 0; i < size; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("r%d", i)), i*10)
+	}
+	assert.Equal(t, size, m.Size())
+	assert.Equal(t, size, m.tables[0].size())
+
+	for i := 0; i < size; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("r%d", i)))
+		assert.True(t, ok)
+		assert.Equal(t, i*10, actual)
+	}
+}
+
+func TestHashMapRehashCompletes(t *testing.T) {
+	m, _ := newHashMap(4)
+
+	for i := 0; i < 1000; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+	for m.isRehashing() {
+		m.Put(NewStringKey("0"), 0)
+	}
+
+	assert.Nil(t, m.tables[1])
+	assert.Equal(t, 1000, m.Size())
+	assert.Equal(t, 1000, m.tables[0].size())
+	for i := 0; i < 1000; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+	}
+}
+
+// TestHashMapConcurrentRehash runs readers and writers in parallel on a
+// map that keeps rehashing, run it with -race.
+func TestHashMapConcurrentRehash(t *testing.T) {
+	m := newRehashingMap(t)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := NewStringKey(fmt.Sprintf("w%d-%d", g, i))
+				m.Put(key, i)
+				if i%3 == 0 {
+					m.Delete(key)
+				}
+			}
+		}(g)
+	}
+
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := NewStringKey(fmt.Sprintf("r%d", i%5))
+				if actual, ok := m.Get(key); !ok || actual != i%5 {
+					t.Errorf("get %s: %v %v", key, actual, ok)
+					return
+				}
+				m.Get(NewStringKey(fmt.Sprintf("w%d-%d", g, i)))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	expected := 5
+	for g := 0; g < 8; g++ {
+		for i := 0; i < 2000; i++ {
+			actual, ok := m.Get(NewStringKey(fmt.Sprintf("w%d-%d", g, i)))
+			if i%3 == 0 {
+				assert.False(t, ok)
+				continue
+			}
+			expected++
+			assert.True(t, ok)
+			assert.Equal(t, i, actual)
+		}
+	}
+	assert.Equal(t, expected, m.Size())
+}
+
+// TestHashMapGetWhileResizing checks that the lock-free Get never misses
+// a key present all along while the map keeps growing and shrinking, run
+// it with -race.
+func TestHashMapGetWhileResizing(t *testing.T) {
+	m, _ := newHashMap(4)
+	for i := 0; i < 100; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("stable-%d", i)), i)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for r := 0; r < 20; r++ {
+			fill(m, 2000)
+			for i := 0; i < 100; i++ {
+				m.Put(NewStringKey(fmt.Sprintf("stable-%d", i)), i)
+			}
+			drain(m, 2000)
+		}
+		close(done)
+	}()
+
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				key := NewStringKey(fmt.Sprintf("stable-%d", i%100))
+				if actual, ok := m.Get(key); !ok || actual != i%100 {
+					t.Errorf("get %s: %v %v", key, actual, ok)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.True(t, m.Stats().Shrinks > 0)
+}
+
+func TestHashMapRange(t *testing.T) {
+	m, _ := NewHashMap(16)
+
+	for i := 0; i < 100; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+
+	visited := make(map[string]interface{})
+	m.Range(func(k Key, v interface{}) bool {
+		_, dup := visited[k.String()]
+		assert.False(t, dup, "key %s", k)
+		visited[k.String()] = v
+		return true
+	})
+
+	assert.Equal(t, 100, len(visited))
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, i, visited[fmt.Sprintf("%d", i)])
+	}
+}
+
+func TestHashMapRangeStop(t *testing.T) {
+	m, _ := NewHashMap(16)
+
+	for i := 0; i < 100; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+
+	cnt := 0
+	m.Range(func(k Key, v interface{}) bool {
+		cnt++
+		return cnt < 10
+	})
+	assert.Equal(t, 10, cnt)
+}
+
+func TestHashMapRangeWhileRehashing(t *testing.T) {
+	m := newRehashingMap(t)
+	m.Put(NewStringKey("r0"), 0)
+	assert.True(t, m.isRehashing())
+	assert.True(t, m.tables[1].size() > 0)
+
+	visited := make(map[string]interface{})
+	m.Range(func(k Key, v interface{}) bool {
+		visited[k.String()] = v
+		return true
+	})
+
+	assert.Equal(t, m.Size(), len(visited))
+	for i := 0; i < m.Size(); i++ {
+		assert.Equal(t, i, visited[fmt.Sprintf("r%d", i)])
+	}
+}
+
+func TestHashMapRangeMutate(t *testing.T) {
+	m, _ := NewHashMap(4)
+
+	for i := 0; i < 100; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+
+	// f may write to the map it is ranging over.
+	m.Range(func(k Key, v interface{}) bool {
+		m.Delete(k)
+		m.Put(NewStringKey("new-"+k.String()), v)
+		return true
+	})
+
+	for i := 0; i < 100; i++ {
+		_, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.False(t, ok)
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("new-%d", i)))
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+	}
+}
+
+// collisionSet returns n keys whose unseeded hashes all fall into the
+// first bucket of a table of size buckets, as an attacker knowing the
+// hash function would craft them.
+func collisionSet(n, size int) []Key {
+	keys := make([]Key, 0, n)
+	for i := 0; len(keys) < n; i++ {
+		k := NewStringKey(fmt.Sprintf("attack-%d", i))
+		if k.Hash()&(size-1) == 0 {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func TestHashMapRandomSeed(t *testing.T) {
+	m1, _ := newHashMap(16)
+	m2, _ := newHashMap(16)
+	assert.NotEqual(t, uint64(0), m1.seed)
+	assert.NotEqual(t, m1.seed, m2.seed)
+}
+
+func TestHashMapFixedSeed(t *testing.T) {
+	m1, _ := newHashMapConfig(1024, mustConfig(t, WithSeed(42)))
+	m2, _ := newHashMapConfig(1024, mustConfig(t, WithSeed(42)))
+	assert.Equal(t, uint64(42), m1.seed)
+
+	for i := 0; i < 100; i++ {
+		k := NewStringKey(fmt.Sprintf("%d", i))
+		assert.Equal(t, m1.hashOf(k), m2.hashOf(k))
+	}
+}
+
+func TestHashMapResistsCollisions(t *testing.T) {
+	keys := collisionSet(32, 1024)
+
+	// without a seed every key lands in the same bucket.
+	ht, _ := newHtable(1024)
+	for i, k := range keys {
+		ht.put(k.Hash(), newEntry(k, i))
+	}
+	assert.Equal(t, 32, ht.longestChain())
+
+	m, _ := newHashMap(1024)
+	for i, k := range keys {
+		m.Put(k, i)
+	}
+	assert.True(t, m.Stats().LongestChain < 8, "chain %d", m.Stats().LongestChain)
+
+	for i, k := range keys {
+		actual, ok := m.Get(k)
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+	}
+}
+
+// drain deletes n keys put by fill and makes sure h ends any rehash.
+func drain(h *hashMap, n int) {
+	for i := 0; i < n; i++ {
+		h.Delete(NewStringKey(fmt.Sprintf("%d", i)))
+	}
+	for h.isRehashing() {
+		h.Delete(NewStringKey("absent"))
+	}
+}
+
+func fill(h *hashMap, n int) {
+	for i := 0; i < n; i++ {
+		h.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+	}
+}
+
+func TestHashMapShrink(t *testing.T) {
+	m, _ := newHashMap(16)
+
+	fill(m, 10000)
+	assert.True(t, m.Stats().Buckets >= 8192)
+
+	// removing most of the entries shrinks the table.
+	for i := 0; i < 9900; i++ {
+		m.Delete(NewStringKey(fmt.Sprintf("%d", i)))
+	}
+	st := m.Stats()
+	assert.True(t, st.Shrinks > 0)
+	assert.True(t, st.Buckets <= 1024, "buckets %d", st.Buckets)
+
+	for i := 9900; i < 10000; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+	}
+
+	drain(m, 10000)
+	assert.Equal(t, 0, m.Size())
+	assert.Equal(t, 16, m.Stats().Buckets)
+}
+
+func TestHashMapShrinkNotBelowInitialSize(t *testing.T) {
+	m, _ := newHashMap(256)
+
+	fill(m, 1000)
+	drain(m, 1000)
+	assert.Equal(t, 256, m.Stats().Buckets)
+}
+
+func TestHashMapShrinkDisabled(t *testing.T) {
+	m, _ := newHashMapConfig(16, mustConfig(t, WithMinLoadFactor(0)))
+
+	fill(m, 1000)
+	buckets := m.Stats().Buckets
+	drain(m, 1000)
+
+	assert.Equal(t, buckets, m.Stats().Buckets)
+	assert.Equal(t, int64(0), m.Stats().Shrinks)
+}
+
+func TestHashMapShrinkRefill(t *testing.T) {
+	m, _ := newHashMap(16)
+
+	for r := 0; r < 3; r++ {
+		fill(m, 5000)
+		assert.Equal(t, 5000, m.Size())
+		drain(m, 5000)
+		assert.Equal(t, 0, m.Size())
+		assert.Equal(t, 16, m.Stats().Buckets)
+	}
+}
+
+func TestHashMapShrinkReleasesMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping memory test in short mode")
+	}
+
+	var full, drained runtime.MemStats
+	m, _ := newHashMap(16)
+
+	fill(m, 100000)
+	runtime.GC()
+	runtime.ReadMemStats(&full)
+
+	drain(m, 100000)
+	runtime.GC()
+	runtime.ReadMemStats(&drained)
+
+	assert.Equal(t, 16, m.Stats().Buckets)
+	assert.True(t, drained.HeapAlloc < full.HeapAlloc/4,
+		"heap %d after drain, %d when full", drained.HeapAlloc, full.HeapAlloc)
+	runtime.KeepAlive(m)
+}
+
 func BenchmarkHashMapPut(b *testing.B) {
 	m, _ := NewHashMap(100)
 
@@ -218,13 +686,13 @@ func BenchmarkNative(b *testing.B) {
 }
 
 func showSimpleMap(m *hashMap) {
-	for _, b := range m.tables[0].buckets {
-		fmt.Printf("%s\n", b.String())
+	for i := 0; i < m.tables[0].numBuckets(); i++ {
+		fmt.Printf("%v\n", m.tables[0].bucketEntries(i))
 	}
 	fmt.Printf("----------------------\n")
 	if m.tables[1] != nil {
-		for _, b := range m.tables[1].buckets {
-			fmt.Printf("%s\n", b.String())
+		for i := 0; i < m.tables[1].numBuckets(); i++ {
+			fmt.Printf("%v\n", m.tables[1].bucketEntries(i))
 		}
 	}
 }
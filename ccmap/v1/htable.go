@@ -3,9 +3,38 @@ package v1
 import (
 	"fmt"
 
+	"github.com/csimplestring/go-concurrent-map/ccmap"
 	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
 )
 
+// table stores the entries of a hashMap. Its writers must be serialized,
+// get may run concurrently with them.
+type table interface {
+	// get, put, delete and push take the hash of the key.
+	get(hash int, key Key) (Entry, bool)
+	put(hash int, en Entry) int
+	delete(hash int, key Key) (Entry, int)
+	push(hash int, en Entry) bool
+
+	// len returns the size t was created with, the load factor of t is
+	// its number of entries divided by it.
+	len() int
+
+	// numBuckets returns the number of buckets of t, rehash moves them
+	// one at a time with bucketLen, bucketEntries and clearBucket.
+	numBuckets() int
+	bucketLen(i int) int
+	bucketEntries(i int) []Entry
+	clearBucket(i int)
+
+	size() int
+	// tombstones returns the number of deleted slots that still lengthen
+	// the probes of t, always 0 for chained tables.
+	tombstones() int
+	longestChain() int
+	each(f func(Entry) bool) bool
+}
+
 // htable is the underlying hash tables. It stores
 // <key, value> pairs in buckets.
 type htable struct {
@@ -14,18 +43,17 @@ type htable struct {
 }
 
 // newHtable creates a new empty htable with specified size.
-// Note that size should always be 2^n.
+// size must be a power of two no bigger than ccmap.MAX_TABLE_SIZE, see
+// tableSizeFor.
 func newHtable(size int) (*htable, error) {
-	if size < 0 {
-		return nil,
-			fmt.Errorf("Illegal arg: %d, size of tables should be positive.")
+	if size <= 0 || size&(size-1) != 0 {
+		return nil, fmt.Errorf("%w: table size %d is not a positive power of two",
+			ccmap.ErrInvalidCapacity, size)
 	}
-
-	n := 1
-	for n < size {
-		n = n << 1
+	if size > ccmap.MAX_TABLE_SIZE {
+		return nil, fmt.Errorf("%w: table size %d, max %d",
+			ccmap.ErrCapacityExceeded, size, ccmap.MAX_TABLE_SIZE)
 	}
-	size = n
 
 	buckets := make([]Bucket, size)
 	for i := 0; i < size; i++ {
@@ -38,35 +66,78 @@ func newHtable(size int) (*htable, error) {
 	}, nil
 }
 
+// tableSizeFor rounds n up to a valid table size.
+func tableSizeFor(n int) (int, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("%w: size %d should be positive", ccmap.ErrInvalidCapacity, n)
+	}
+	if n > ccmap.MAX_TABLE_SIZE {
+		return 0, fmt.Errorf("%w: size %d, max %d", ccmap.ErrCapacityExceeded, n, ccmap.MAX_TABLE_SIZE)
+	}
+
+	size := 1
+	for size < n {
+		size = size << 1
+	}
+	return size, nil
+}
+
 // indexFor gives index of bucket for hash. It equals MOD operator.
 func (ht *htable) indexFor(hash int) int {
 	return hash & ht.mask
 }
 
-// get gets Entry based on key.
-func (ht *htable) get(key Key) (Entry, bool) {
-	index := ht.indexFor(key.Hash())
+// get gets Entry based on key, hash is the hash of key.
+func (ht *htable) get(hash int, key Key) (Entry, bool) {
+	index := ht.indexFor(hash)
 	return ht.buckets[index].Get(key)
 }
 
-// put puts en at the beginning of bucket.
-func (ht *htable) put(en Entry) int {
-	index := ht.indexFor(en.Key().Hash())
+// put puts en at the beginning of bucket, hash is the hash of its key.
+func (ht *htable) put(hash int, en Entry) int {
+	index := ht.indexFor(hash)
 	return ht.buckets[index].Put(en)
 }
 
-// delete deletes value based on key.
-func (ht *htable) delete(key Key) (Entry, int) {
-	index := ht.indexFor(key.Hash())
+// delete deletes value based on key, hash is the hash of key.
+func (ht *htable) delete(hash int, key Key) (Entry, int) {
+	index := ht.indexFor(hash)
 	return ht.buckets[index].Delete(key)
 }
 
-// push inserts en at the end of bucket.
-func (ht *htable) push(en Entry) bool {
-	index := ht.indexFor(en.Key().Hash())
+// push inserts en at the end of bucket, hash is the hash of its key.
+func (ht *htable) push(hash int, en Entry) bool {
+	index := ht.indexFor(hash)
 	return ht.buckets[index].Push(en)
 }
 
+// len returns the number of buckets.
+func (ht *htable) len() int {
+	return len(ht.buckets)
+}
+
+// numBuckets returns the number of buckets.
+func (ht *htable) numBuckets() int {
+	return len(ht.buckets)
+}
+
+// bucketLen returns the number of entries in bucket i.
+func (ht *htable) bucketLen(i int) int {
+	return ht.buckets[i].Size()
+}
+
+// bucketEntries returns the entries in bucket i.
+func (ht *htable) bucketEntries(i int) []Entry {
+	return ht.buckets[i].Entries()
+}
+
+// clearBucket removes the entries of bucket i.
+func (ht *htable) clearBucket(i int) {
+	b := ht.buckets[i]
+	for _, ok := b.Pop(); ok; _, ok = b.Pop() {
+	}
+}
+
 // size returns the number of entries in the buckets.
 func (ht *htable) size() int {
 	cnt := 0
@@ -75,3 +146,32 @@ func (ht *htable) size() int {
 	}
 	return cnt
 }
+
+// tombstones returns 0, deleting from a bucket leaves nothing behind.
+func (ht *htable) tombstones() int {
+	return 0
+}
+
+// longestChain returns the number of entries in the fullest bucket.
+func (ht *htable) longestChain() int {
+	longest := 0
+	for _, b := range ht.buckets {
+		if b.Size() > longest {
+			longest = b.Size()
+		}
+	}
+	return longest
+}
+
+// each calls f for every entry until f returns false.
+// It returns false if the iteration was stopped by f.
+func (ht *htable) each(f func(Entry) bool) bool {
+	for _, b := range ht.buckets {
+		for _, en := range b.Entries() {
+			if !f(en) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -3,24 +3,44 @@ package v1
 import (
 	"testing"
 
+	"github.com/csimplestring/go-concurrent-map/ccmap"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewHtableOk(t *testing.T) {
-	h, err := newHtable(3)
+	h, err := newHtable(4)
 	assert.NoError(t, err)
 	assert.Equal(t, 4, len(h.buckets))
 
-	h, _ = newHtable(15)
-	assert.Equal(t, 16, len(h.buckets))
-
-	h, _ = newHtable(24)
-	assert.Equal(t, 32, len(h.buckets))
+	h, _ = newHtable(1)
+	assert.Equal(t, 1, len(h.buckets))
 }
 
 func TestNewHtableError(t *testing.T) {
-	_, err := newHtable(-1)
-	assert.Error(t, err)
+	for _, size := range []int{-1, 0, 3, 24} {
+		_, err := newHtable(size)
+		assert.ErrorIs(t, err, ccmap.ErrInvalidCapacity)
+	}
+
+	_, err := newHtable(ccmap.MAX_TABLE_SIZE << 1)
+	assert.ErrorIs(t, err, ccmap.ErrCapacityExceeded)
+}
+
+func TestTableSizeFor(t *testing.T) {
+	tests := []struct{ n, size int }{
+		{1, 1}, {3, 4}, {15, 16}, {16, 16}, {24, 32},
+		{ccmap.MAX_TABLE_SIZE, ccmap.MAX_TABLE_SIZE},
+	}
+	for _, tt := range tests {
+		size, err := tableSizeFor(tt.n)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.size, size)
+	}
+
+	_, err := tableSizeFor(0)
+	assert.ErrorIs(t, err, ccmap.ErrInvalidCapacity)
+	_, err = tableSizeFor(ccmap.MAX_TABLE_SIZE + 1)
+	assert.ErrorIs(t, err, ccmap.ErrCapacityExceeded)
 }
 
 func TestHtableIndexFor(t *testing.T) {
@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"iter"
+
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
+
+// All returns an iterator over the <key, value> pairs of h.
+func (h *hashMap) All() iter.Seq2[Key, any] {
+	return h.Range
+}
+
+// Keys returns an iterator over the keys of h.
+func (h *hashMap) Keys() iter.Seq[Key] {
+	return keys(h.Range)
+}
+
+// Values returns an iterator over the values of h.
+func (h *hashMap) Values() iter.Seq[any] {
+	return values(h.Range)
+}
+
+// All returns an iterator over the <key, value> pairs of c.
+func (c *concurrentHashMap) All() iter.Seq2[Key, any] {
+	return c.Range
+}
+
+// Keys returns an iterator over the keys of c.
+func (c *concurrentHashMap) Keys() iter.Seq[Key] {
+	return keys(c.Range)
+}
+
+// Values returns an iterator over the values of c.
+func (c *concurrentHashMap) Values() iter.Seq[any] {
+	return values(c.Range)
+}
+
+// keys turns seq into an iterator over its keys.
+func keys(seq iter.Seq2[Key, any]) iter.Seq[Key] {
+	return func(yield func(Key) bool) {
+		seq(func(k Key, _ any) bool {
+			return yield(k)
+		})
+	}
+}
+
+// values turns seq into an iterator over its values.
+func values(seq iter.Seq2[Key, any]) iter.Seq[any] {
+	return func(yield func(any) bool) {
+		seq(func(_ Key, v any) bool {
+			return yield(v)
+		})
+	}
+}
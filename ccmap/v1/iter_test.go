@@ -0,0 +1,22 @@
+package v1
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllWhileRehashing(t *testing.T) {
+	m := newRehashingMap(t)
+	m.Put(NewStringKey("r0"), 0)
+	assert.True(t, m.isRehashing())
+
+	cnt := 0
+	for k, v := range m.All() {
+		assert.Equal(t, fmt.Sprintf("r%d", v), k.String())
+		cnt++
+	}
+	assert.Equal(t, m.Size(), cnt)
+}
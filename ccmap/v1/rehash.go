@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// rehashWorker moves the buckets of a rehashing hashMap in background.
+type rehashWorker struct {
+	budget time.Duration
+	// kick wakes the worker up when a rehash begins.
+	kick chan struct{}
+	// done is closed to stop the worker.
+	done    chan struct{}
+	stopped sync.WaitGroup
+	once    sync.Once
+}
+
+// wake wakes the worker up, it never blocks.
+func (w *rehashWorker) wake() {
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+}
+
+// startWorker starts the background worker of h.
+func (h *hashMap) startWorker(budget time.Duration) {
+	w := &rehashWorker{
+		budget: budget,
+		kick:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	h.worker = w
+
+	w.stopped.Add(1)
+	go func() {
+		defer w.stopped.Done()
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-w.kick:
+			}
+
+			for h.rehashFor(w.budget) {
+				select {
+				case <-w.done:
+					return
+				default:
+				}
+				// let the writers waiting for the lock in.
+				runtime.Gosched()
+			}
+		}
+	}()
+}
+
+// overloaded returns true if the entries of h load tables[1] beyond
+// maxRehashLoad: the writers add entries faster than the background worker
+// moves them, so tables[1] would keep filling up before it can grow.
+// It must only be called while holding the lock and rehashing.
+func (h *hashMap) overloaded() bool {
+	return float64(h.Len()) >= float64(h.tables[1].len())*h.maxRehashLoad
+}
+
+// rehashFor moves buckets under the write lock until the rehash ends or
+// budget is spent. It returns true if h is still rehashing.
+func (h *hashMap) rehashFor(budget time.Duration) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	deadline := time.Now().Add(budget)
+	for h.isRehashing() {
+		h.rehash()
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+
+	if !h.isRehashing() {
+		// the table may need another resize, e.g. after mass deletes.
+		// A failed one is reported by the next put.
+		h.resize()
+	}
+	return h.isRehashing()
+}
+
+// Close stops the background worker of h, if any. Rehashing then falls
+// back to RehashInline, so h stays usable. Close may be called more
+// than once.
+func (h *hashMap) Close() {
+	h.mutex.RLock()
+	w := h.worker
+	h.mutex.RUnlock()
+	if w == nil {
+		return
+	}
+
+	w.once.Do(func() {
+		close(w.done)
+	})
+	w.stopped.Wait()
+
+	h.mutex.Lock()
+	h.worker = nil
+	h.mutex.Unlock()
+}
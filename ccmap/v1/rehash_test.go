@@ -0,0 +1,160 @@
+package v1
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBackgroundMap(t *testing.T, budget time.Duration) *hashMap {
+	m, err := newHashMapConfig(16, mustConfig(t,
+		WithRehashMode(RehashBackground),
+		WithRehashBudget(budget),
+	))
+	assert.NoError(t, err)
+	return m
+}
+
+// waitRehash waits until h is not rehashing any more.
+func waitRehash(t *testing.T, h *hashMap) {
+	deadline := time.Now().Add(5 * time.Second)
+	for h.Stats().Rehashing {
+		if time.Now().After(deadline) {
+			t.Fatal("rehash did not complete")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBackgroundRehashCompletesIdle(t *testing.T) {
+	m := newBackgroundMap(t, time.Microsecond)
+	defer m.Close()
+
+	// writers only move buckets when the worker falls behind, so the map
+	// is likely left mid-rehash.
+	fill(m, 10000)
+	waitRehash(t, m)
+
+	st := m.Stats()
+	assert.Equal(t, 10000, st.Entries)
+	assert.True(t, st.Buckets >= 8192)
+	for i := 0; i < 10000; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+	}
+}
+
+func TestBackgroundShrinkCompletesIdle(t *testing.T) {
+	m := newBackgroundMap(t, time.Microsecond)
+	defer m.Close()
+
+	fill(m, 10000)
+	for i := 0; i < 10000; i++ {
+		m.Delete(NewStringKey(fmt.Sprintf("%d", i)))
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Stats().Buckets != 16 {
+		if time.Now().After(deadline) {
+			t.Fatalf("table not shrunk, %d buckets", m.Stats().Buckets)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 0, m.Size())
+}
+
+// TestBackgroundRehashBackPressure writes in a tight loop, faster than
+// the worker rehashes, and checks that the writers help it before the
+// chains get long.
+func TestBackgroundRehashBackPressure(t *testing.T) {
+	m := newBackgroundMap(t, time.Nanosecond)
+	defer m.Close()
+
+	maxLoad := m.maxLoadFactor * OVERLOAD_FACTOR
+	for i := 0; i < 100000; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("%d", i)), i)
+		if i%1000 == 0 {
+			st := m.Stats()
+			assert.True(t, st.LoadFactor <= maxLoad+0.01, "load factor %g", st.LoadFactor)
+			assert.True(t, st.LongestChain <= 16, "longest chain %d", st.LongestChain)
+		}
+	}
+	assert.Equal(t, 100000, m.Len())
+}
+
+func TestBackgroundRehashAfterClose(t *testing.T) {
+	m := newBackgroundMap(t, time.Microsecond)
+	m.Close()
+
+	// with the worker stopped, the map rehashes inline again.
+	fill(m, 1000)
+	for m.isRehashing() {
+		m.Put(NewStringKey("0"), 0)
+	}
+	assert.Equal(t, 1000, m.Size())
+	assert.Nil(t, m.worker)
+}
+
+func TestBackgroundRehashClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	c, _ := New(WithSegments(8), WithRehashMode(RehashBackground))
+	assert.Equal(t, before+8, runtime.NumGoroutine())
+
+	c.Close()
+	c.Close()
+
+	// the workers may need a moment to exit once they are done.
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left, %d before", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.Put(NewStringKey("k1"), 1)
+	actual, ok := c.Get(NewStringKey("k1"))
+	assert.True(t, ok)
+	assert.Equal(t, 1, actual)
+}
+
+func TestBackgroundRehashConcurrent(t *testing.T) {
+	c, _ := New(
+		WithSegments(4),
+		WithRehashMode(RehashBackground),
+		WithRehashBudget(time.Microsecond),
+	)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := NewStringKey(fmt.Sprintf("%d-%d", g, i))
+				c.Put(key, i)
+				if actual, ok := c.Get(key); !ok || actual != i {
+					t.Errorf("get %s: %v %v", key, actual, ok)
+					return
+				}
+				if i%2 == 0 {
+					c.Delete(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for _, s := range c.(*concurrentHashMap).load().segments {
+		waitRehash(t, s)
+	}
+	assert.Equal(t, 8000, c.Stats().Entries)
+}
@@ -0,0 +1,151 @@
+package v1
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// SegmentStats is a snapshot of the statistics of one segment.
+type SegmentStats struct {
+	// Entries is the number of entries stored.
+	Entries int
+	// Buckets is the number of buckets entries are stored in, or of
+	// slots with LayoutOpenAddressing. While rehashing it is the size of
+	// the table entries are moved to.
+	Buckets int
+	// LoadFactor equals Entries / Buckets.
+	LoadFactor float64
+	// LongestChain is the number of entries in the fullest bucket, or
+	// group of slots with LayoutOpenAddressing.
+	LongestChain int
+	// Rehashing tells if entries are being moved to a bigger table.
+	Rehashing bool
+	// Rehashes is the number of rehashes started so far.
+	Rehashes int64
+	// Shrinks is the number of the rehashes that shrank the table.
+	Shrinks int64
+
+	// A Put hits if the key was present and its value got replaced,
+	// Get and Delete hit if the key was found.
+	PutHits      int64
+	PutMisses    int64
+	GetHits      int64
+	GetMisses    int64
+	DeleteHits   int64
+	DeleteMisses int64
+	// Expirations is the number of expired entries deleted.
+	Expirations int64
+	// Evictions is the number of entries evicted because their segment
+	// was full, see WithMaxEntries.
+	Evictions int64
+}
+
+// HitRatio returns the ratio of the calls to Get that found their key,
+// or 0 if Get was never called.
+func (s SegmentStats) HitRatio() float64 {
+	if gets := s.GetHits + s.GetMisses; gets > 0 {
+		return float64(s.GetHits) / float64(gets)
+	}
+	return 0
+}
+
+// Stats is a snapshot of the statistics of a concurrent map. The embedded
+// SegmentStats sums up all the segments, its LongestChain is the longest
+// chain of any segment.
+type Stats struct {
+	SegmentStats
+	Segments []SegmentStats
+}
+
+// add accumulates s into the totals of st.
+func (st *Stats) add(s SegmentStats) {
+	st.Entries += s.Entries
+	st.Buckets += s.Buckets
+	st.Rehashes += s.Rehashes
+	st.Shrinks += s.Shrinks
+	st.Rehashing = st.Rehashing || s.Rehashing
+	if s.LongestChain > st.LongestChain {
+		st.LongestChain = s.LongestChain
+	}
+
+	st.PutHits += s.PutHits
+	st.PutMisses += s.PutMisses
+	st.GetHits += s.GetHits
+	st.GetMisses += s.GetMisses
+	st.DeleteHits += s.DeleteHits
+	st.DeleteMisses += s.DeleteMisses
+	st.Expirations += s.Expirations
+	st.Evictions += s.Evictions
+
+	if st.Buckets > 0 {
+		st.LoadFactor = float64(st.Entries) / float64(st.Buckets)
+	}
+}
+
+// counters records the operations of a segment. The fields are only
+// touched atomically, so readers holding the shared lock may update them.
+// Get, which takes no lock, records into striped counters instead, see
+// hitCounter.
+type counters struct {
+	rehashes     atomic.Int64
+	shrinks      atomic.Int64
+	putHits      atomic.Int64
+	putMisses    atomic.Int64
+	gets         hitCounter
+	deleteHits   atomic.Int64
+	deleteMisses atomic.Int64
+	expirations  atomic.Int64
+	evictions    atomic.Int64
+}
+
+// MAX_GET_STRIPES caps the stripes of the Get counters of a segment, the
+// segments already spread the readers of a map.
+const MAX_GET_STRIPES = 8
+
+// hitStripe is one stripe of a hitCounter, padded to a cache line.
+type hitStripe struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+	_      [48]byte
+}
+
+// hitCounter counts hits and misses over stripes picked at random, so
+// that concurrent readers seldom write to the same cache line. Its zero
+// value must be initialized by init.
+type hitCounter struct {
+	stripes []hitStripe
+}
+
+// init allocates n stripes, n is rounded up to a power of two and capped
+// at MAX_GET_STRIPES.
+func (c *hitCounter) init(n int) {
+	size := 1
+	for size < min(n, MAX_GET_STRIPES) {
+		size <<= 1
+	}
+	c.stripes = make([]hitStripe, size)
+}
+
+// record increments the hits if ok, otherwise the misses.
+func (c *hitCounter) record(ok bool) {
+	s := &c.stripes[rand.Uint32()&uint32(len(c.stripes)-1)]
+	record(ok, &s.hits, &s.misses)
+}
+
+// load returns the sums of the hits and misses of all the stripes.
+func (c *hitCounter) load() (hits, misses int64) {
+	for i := range c.stripes {
+		hits += c.stripes[i].hits.Load()
+		misses += c.stripes[i].misses.Load()
+	}
+	return hits, misses
+}
+
+// record increments hit if ok, otherwise miss.
+func record(ok bool, hit, miss *atomic.Int64) {
+	if ok {
+		hit.Add(1)
+	} else {
+		miss.Add(1)
+	}
+}
@@ -0,0 +1,304 @@
+package v1
+
+import (
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+)
+
+const (
+	// groupSize is the number of slots sharing a control word.
+	groupSize = 8
+
+	// a control byte is ctrlEmpty, ctrlDeleted, or the 7 low bits of the
+	// hash of the entry in its slot.
+	ctrlEmpty   = 0x80
+	ctrlDeleted = 0xfe
+
+	ctrlLSB = 0x0101010101010101
+	ctrlMSB = 0x8080808080808080
+)
+
+// ctrlWord holds the control bytes of a group, byte i for slot i.
+type ctrlWord uint64
+
+// matchH2 returns the slots whose control byte is h2. It may report a
+// few false positives, never false negatives.
+func (c ctrlWord) matchH2(h2 uint8) slotSet {
+	v := uint64(c) ^ (ctrlLSB * uint64(h2))
+	return slotSet(((v - ctrlLSB) &^ v) & ctrlMSB)
+}
+
+// matchEmpty returns the empty slots.
+func (c ctrlWord) matchEmpty() slotSet {
+	v := uint64(c)
+	return slotSet((v &^ (v << 6)) & ctrlMSB)
+}
+
+// matchFree returns the empty and deleted slots.
+func (c ctrlWord) matchFree() slotSet {
+	return slotSet(uint64(c) & ctrlMSB)
+}
+
+// matchFull returns the slots holding an entry.
+func (c ctrlWord) matchFull() slotSet {
+	return slotSet(^uint64(c) & ctrlMSB)
+}
+
+// at returns the control byte of slot i.
+func (c ctrlWord) at(i int) uint8 {
+	return uint8(c >> (uint(i) * 8))
+}
+
+// set returns c with the control byte of slot i set to b.
+func (c ctrlWord) set(i int, b uint8) ctrlWord {
+	shift := uint(i) * 8
+	return ctrlWord(uint64(c)&^(0xff<<shift) | uint64(b)<<shift)
+}
+
+// slotSet is a set of slots of a group, the high bit of byte i is set if
+// slot i is in the set.
+type slotSet uint64
+
+// first returns the lowest slot of s, s must not be empty.
+func (s slotSet) first() int {
+	return bits.TrailingZeros64(uint64(s)) >> 3
+}
+
+// next returns s without its lowest slot.
+func (s slotSet) next() slotSet {
+	return s & (s - 1)
+}
+
+// group is groupSize slots and their control word.
+type group struct {
+	ctrl  atomic.Uint64
+	slots [groupSize]atomic.Pointer[entry]
+}
+
+// swissTable is an open-addressing table in the style of Swiss tables:
+// entries are stored right in slots, grouped by 8, and a probe compares
+// the 7 low bits of the hash to the 8 control bytes of a group at once.
+// Groups are probed quadratically starting from the one picked by the
+// rest of the hash.
+//
+// Get runs without a lock: an entry is stored in its slot before its
+// control byte is set and entries are never modified. A probe stops at
+// the first group with an empty slot, so a deleted slot only becomes
+// empty again if its group already has an empty one: no probe ever went
+// past that group. Otherwise it becomes a tombstone that insertions
+// reuse. Tombstones lengthen probes like entries, the hashMap counts them
+// to rehash the table before they take all the empty slots.
+type swissTable struct {
+	mask    int
+	groups  []group
+	cnt     int
+	deleted int
+}
+
+// newSwissTable creates a new empty swissTable with size slots, size must
+// be a power of two no bigger than ccmap.MAX_TABLE_SIZE. It has at least
+// one group.
+func newSwissTable(size int) (*swissTable, error) {
+	if size <= 0 || size&(size-1) != 0 {
+		return nil, fmt.Errorf("%w: table size %d is not a positive power of two",
+			ccmap.ErrInvalidCapacity, size)
+	}
+	if size > ccmap.MAX_TABLE_SIZE {
+		return nil, fmt.Errorf("%w: table size %d, max %d",
+			ccmap.ErrCapacityExceeded, size, ccmap.MAX_TABLE_SIZE)
+	}
+
+	n := size / groupSize
+	if n == 0 {
+		n = 1
+	}
+
+	groups := make([]group, n)
+	for i := range groups {
+		groups[i].ctrl.Store(ctrlLSB * ctrlEmpty)
+	}
+	return &swissTable{
+		mask:   n - 1,
+		groups: groups,
+	}, nil
+}
+
+// probe returns the index of the i-th group to visit for hash.
+func (st *swissTable) probe(hash int, i int) int {
+	return (int(uint(hash)>>7) + i*(i+1)/2) & st.mask
+}
+
+// h2 returns the control byte of hash.
+func h2(hash int) uint8 {
+	return uint8(hash & 0x7f)
+}
+
+// find returns the group and slot of key, or -1 if key is absent.
+func (st *swissTable) find(hash int, key Key) (*group, int, *entry) {
+	for i := 0; i < len(st.groups); i++ {
+		g := &st.groups[st.probe(hash, i)]
+		ctrl := ctrlWord(g.ctrl.Load())
+		for s := ctrl.matchH2(h2(hash)); s != 0; s = s.next() {
+			slot := s.first()
+			if en := g.slots[slot].Load(); en != nil && en.k.Equal(key) {
+				return g, slot, en
+			}
+		}
+		if ctrl.matchEmpty() != 0 {
+			break
+		}
+	}
+	return nil, -1, nil
+}
+
+// get gets Entry based on key, hash is the hash of key.
+func (st *swissTable) get(hash int, key Key) (Entry, bool) {
+	if _, _, en := st.find(hash, key); en != nil {
+		return en, true
+	}
+	return nil, false
+}
+
+// put stores en in a slot, hash is the hash of its key. It replaces the
+// entry of the same key or takes the first free slot of the probe.
+func (st *swissTable) put(hash int, en Entry) int {
+	e := toEntry(en)
+	if g, slot, _ := st.find(hash, e.k); g != nil {
+		g.slots[slot].Store(e)
+		return entryReplace
+	}
+
+	for i := 0; i < len(st.groups); i++ {
+		g := &st.groups[st.probe(hash, i)]
+		ctrl := ctrlWord(g.ctrl.Load())
+		if free := ctrl.matchFree(); free != 0 {
+			slot := free.first()
+			if ctrl.at(slot) == ctrlDeleted {
+				st.deleted--
+			}
+			g.slots[slot].Store(e)
+			g.ctrl.Store(uint64(ctrl.set(slot, h2(hash))))
+			st.cnt++
+			return entryAdd
+		}
+	}
+	return entryErr
+}
+
+// delete deletes value based on key, hash is the hash of key.
+func (st *swissTable) delete(hash int, key Key) (Entry, int) {
+	g, slot, en := st.find(hash, key)
+	if g == nil {
+		return nil, 0
+	}
+
+	ctrl := ctrlWord(g.ctrl.Load())
+	g.ctrl.Store(uint64(ctrl.set(slot, st.freed(ctrl, 1))))
+	g.slots[slot].Store(nil)
+	st.cnt--
+	return en, 1
+}
+
+// freed returns the control byte of n slots deleted from the group of
+// ctrl: ctrlEmpty if the group has an empty slot, ctrlDeleted otherwise.
+func (st *swissTable) freed(ctrl ctrlWord, n int) uint8 {
+	if ctrl.matchEmpty() != 0 {
+		return ctrlEmpty
+	}
+	st.deleted += n
+	return ctrlDeleted
+}
+
+// push stores en, hash is the hash of its key.
+func (st *swissTable) push(hash int, en Entry) bool {
+	return st.put(hash, en) != entryErr
+}
+
+// len returns the number of slots.
+func (st *swissTable) len() int {
+	return len(st.groups) * groupSize
+}
+
+// numBuckets returns the number of groups.
+func (st *swissTable) numBuckets() int {
+	return len(st.groups)
+}
+
+// bucketLen returns the number of entries in group i.
+func (st *swissTable) bucketLen(i int) int {
+	full := ctrlWord(st.groups[i].ctrl.Load()).matchFull()
+	return bits.OnesCount64(uint64(full))
+}
+
+// bucketEntries returns the entries in group i.
+func (st *swissTable) bucketEntries(i int) []Entry {
+	g := &st.groups[i]
+	var entries []Entry
+	for s := ctrlWord(g.ctrl.Load()).matchFull(); s != 0; s = s.next() {
+		entries = append(entries, g.slots[s.first()].Load())
+	}
+	return entries
+}
+
+// clearBucket deletes the entries of group i.
+func (st *swissTable) clearBucket(i int) {
+	g := &st.groups[i]
+	ctrl := ctrlWord(g.ctrl.Load())
+	full := ctrl.matchFull()
+	b := st.freed(ctrl, bits.OnesCount64(uint64(full)))
+	for s := full; s != 0; s = s.next() {
+		ctrl = ctrl.set(s.first(), b)
+		st.cnt--
+	}
+	g.ctrl.Store(uint64(ctrl))
+	for slot := range g.slots {
+		g.slots[slot].Store(nil)
+	}
+}
+
+// size returns the number of entries.
+func (st *swissTable) size() int {
+	return st.cnt
+}
+
+// tombstones returns the number of deleted slots.
+func (st *swissTable) tombstones() int {
+	return st.deleted
+}
+
+// longestChain returns the number of entries in the fullest group.
+func (st *swissTable) longestChain() int {
+	longest := 0
+	for i := range st.groups {
+		if n := st.bucketLen(i); n > longest {
+			longest = n
+		}
+	}
+	return longest
+}
+
+// each calls f for every entry until f returns false.
+// It returns false if the iteration was stopped by f.
+func (st *swissTable) each(f func(Entry) bool) bool {
+	for i := range st.groups {
+		g := &st.groups[i]
+		for s := ctrlWord(g.ctrl.Load()).matchFull(); s != 0; s = s.next() {
+			if !f(g.slots[s.first()].Load()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// toEntry returns en as an *entry, copying it if it is another Entry.
+func toEntry(en Entry) *entry {
+	if e, ok := en.(*entry); ok {
+		return e
+	}
+	return &entry{k: en.Key(), v: en.Value()}
+}
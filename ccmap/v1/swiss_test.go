@@ -0,0 +1,357 @@
+package v1
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/csimplestring/go-concurrent-map/ccmap"
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+func newOpenMap(t *testing.T, size int) *hashMap {
+	m, err := newHashMapConfig(size, mustConfig(t, WithTableLayout(LayoutOpenAddressing)))
+	assert.NoError(t, err)
+	return m
+}
+
+func TestCtrlWordMatch(t *testing.T) {
+	ctrl := ctrlWord(ctrlLSB * ctrlEmpty)
+	ctrl = ctrl.set(1, 0x12).set(3, ctrlDeleted).set(6, 0x12).set(7, 0x7f)
+
+	var slots []int
+	for s := ctrl.matchH2(0x12); s != 0; s = s.next() {
+		slots = append(slots, s.first())
+	}
+	assert.Equal(t, []int{1, 6}, slots)
+
+	assert.Equal(t, 4, popCount(ctrl.matchEmpty()))
+	assert.Equal(t, 5, popCount(ctrl.matchFree()))
+	assert.Equal(t, 3, popCount(ctrl.matchFull()))
+	assert.Equal(t, 7, ctrl.matchFull().next().next().first())
+}
+
+func popCount(s slotSet) int {
+	n := 0
+	for ; s != 0; s = s.next() {
+		n++
+	}
+	return n
+}
+
+func TestNewSwissTable(t *testing.T) {
+	st, err := newSwissTable(64)
+	assert.NoError(t, err)
+	assert.Equal(t, 64, st.len())
+	assert.Equal(t, 8, st.numBuckets())
+
+	// a table has at least one group.
+	st, _ = newSwissTable(2)
+	assert.Equal(t, groupSize, st.len())
+
+	_, err = newSwissTable(24)
+	assert.ErrorIs(t, err, ccmap.ErrInvalidCapacity)
+	_, err = newSwissTable(ccmap.MAX_TABLE_SIZE << 1)
+	assert.ErrorIs(t, err, ccmap.ErrCapacityExceeded)
+}
+
+func TestSwissTablePutGetDelete(t *testing.T) {
+	st, _ := newSwissTable(16)
+	hash := func(k Key) int { return HashOf(k, 0) }
+
+	for i := 0; i < 14; i++ {
+		k := NewIntKey(i)
+		assert.Equal(t, entryAdd, st.put(hash(k), newEntry(k, i)))
+	}
+	assert.Equal(t, entryReplace, st.put(hash(NewIntKey(3)), newEntry(NewIntKey(3), 30)))
+	assert.Equal(t, 14, st.size())
+
+	en, ok := st.get(hash(NewIntKey(3)), NewIntKey(3))
+	assert.True(t, ok)
+	assert.Equal(t, 30, en.Value())
+	_, ok = st.get(hash(NewIntKey(14)), NewIntKey(14))
+	assert.False(t, ok)
+
+	en, cnt := st.delete(hash(NewIntKey(5)), NewIntKey(5))
+	assert.Equal(t, 1, cnt)
+	assert.Equal(t, 5, en.Value())
+	_, cnt = st.delete(hash(NewIntKey(5)), NewIntKey(5))
+	assert.Equal(t, 0, cnt)
+	_, ok = st.get(hash(NewIntKey(5)), NewIntKey(5))
+	assert.False(t, ok)
+
+	sum := 0
+	st.each(func(en Entry) bool {
+		sum += en.Value().(int)
+		return true
+	})
+	assert.Equal(t, 13*14/2-5-3+30, sum)
+}
+
+func TestSwissTableReusesTombstones(t *testing.T) {
+	st, _ := newSwissTable(8)
+	hash := func(k Key) int { return HashOf(k, 0) }
+
+	for i := 0; i < 8; i++ {
+		st.put(hash(NewIntKey(i)), newEntry(NewIntKey(i), i))
+	}
+	assert.Equal(t, entryErr, st.put(hash(NewIntKey(8)), newEntry(NewIntKey(8), 8)))
+
+	// the table is full of entries and tombstones, no slot is empty.
+	for r := 0; r < 100; r++ {
+		st.delete(hash(NewIntKey(r)), NewIntKey(r))
+		assert.Equal(t, entryAdd, st.put(hash(NewIntKey(r+8)), newEntry(NewIntKey(r+8), r+8)))
+	}
+	assert.Equal(t, 8, st.size())
+
+	for i := 100; i < 108; i++ {
+		en, ok := st.get(hash(NewIntKey(i)), NewIntKey(i))
+		assert.True(t, ok)
+		assert.Equal(t, i, en.Value())
+	}
+	_, ok := st.get(hash(NewIntKey(0)), NewIntKey(0))
+	assert.False(t, ok)
+}
+
+func TestSwissTableDeleteFreesSlots(t *testing.T) {
+	st, _ := newSwissTable(8)
+	hash := func(k Key) int { return HashOf(k, 0) }
+
+	// the group has empty slots, no probe goes past it.
+	st.put(hash(NewIntKey(1)), newEntry(NewIntKey(1), 1))
+	st.delete(hash(NewIntKey(1)), NewIntKey(1))
+	assert.Equal(t, 0, st.tombstones())
+	assert.Equal(t, 8, popCount(ctrlWord(st.groups[0].ctrl.Load()).matchEmpty()))
+
+	for i := 0; i < 8; i++ {
+		st.put(hash(NewIntKey(i)), newEntry(NewIntKey(i), i))
+	}
+	st.delete(hash(NewIntKey(2)), NewIntKey(2))
+	st.delete(hash(NewIntKey(3)), NewIntKey(3))
+	assert.Equal(t, 2, st.tombstones())
+	st.put(hash(NewIntKey(8)), newEntry(NewIntKey(8), 8))
+	assert.Equal(t, 1, st.tombstones())
+	st.clearBucket(0)
+	assert.Equal(t, 8, st.tombstones())
+}
+
+// probeLen returns the number of groups a lookup of hash visits.
+func probeLen(st *swissTable, hash int) int {
+	for i := 0; i < len(st.groups); i++ {
+		if ctrlWord(st.groups[st.probe(hash, i)].ctrl.Load()).matchEmpty() != 0 {
+			return i + 1
+		}
+	}
+	return len(st.groups)
+}
+
+// TestOpenAddressingChurn deletes and puts keys for long, tombstones must
+// not take all the empty slots and make misses probe every group.
+func TestOpenAddressingChurn(t *testing.T) {
+	m, err := newHashMapConfig(16, mustConfig(t, WithTableLayout(LayoutOpenAddressing), WithSeed(1)))
+	assert.NoError(t, err)
+	fill(m, 1000)
+	for i := 0; i < 100000; i++ {
+		m.Delete(NewStringKey(fmt.Sprintf("%d", i)))
+		m.Put(NewStringKey(fmt.Sprintf("%d", i+1000)), i+1000)
+	}
+	m.finishRehash()
+	assert.Equal(t, 1000, m.Len())
+
+	st := m.tables[0].(*swissTable)
+	empty := 0
+	for i := range st.groups {
+		empty += popCount(ctrlWord(st.groups[i].ctrl.Load()).matchEmpty())
+	}
+	assert.True(t, empty >= st.len()/16, "%d empty slots out of %d", empty, st.len())
+
+	longest, total := 0, 0
+	for i := 0; i < 1000; i++ {
+		n := probeLen(st, m.hashOf(NewStringKey(fmt.Sprintf("miss-%d", i))))
+		longest = max(longest, n)
+		total += n
+	}
+	t.Logf("%d groups, %d empty slots, probe length: avg %.2f, max %d",
+		len(st.groups), empty, float64(total)/1000, longest)
+	assert.True(t, total <= 2*1000, "average probe length %.2f", float64(total)/1000)
+	assert.True(t, longest <= 16, "longest probe %d", longest)
+}
+
+func TestSwissTableBuckets(t *testing.T) {
+	st, _ := newSwissTable(32)
+	hash := func(k Key) int { return HashOf(k, 0) }
+	for i := 0; i < 20; i++ {
+		st.put(hash(NewIntKey(i)), newEntry(NewIntKey(i), i))
+	}
+
+	cnt := 0
+	for i := 0; i < st.numBuckets(); i++ {
+		assert.Equal(t, st.bucketLen(i), len(st.bucketEntries(i)))
+		assert.True(t, st.bucketLen(i) <= st.longestChain())
+		cnt += st.bucketLen(i)
+		st.clearBucket(i)
+		assert.Equal(t, 0, st.bucketLen(i))
+	}
+	assert.Equal(t, 20, cnt)
+	assert.Equal(t, 0, st.size())
+}
+
+func TestOpenAddressingDefaults(t *testing.T) {
+	m, err := New(WithTableLayout(LayoutOpenAddressing))
+	assert.NoError(t, err)
+	for _, s := range m.(*concurrentHashMap).load().segments {
+		assert.Equal(t, MAX_OPEN_LOAD_FACTOR, s.maxLoadFactor)
+	}
+
+	_, err = New(WithTableLayout(LayoutOpenAddressing), WithMaxLoadFactor(1))
+	assert.ErrorIs(t, err, ccmap.ErrInvalidConfig)
+
+	_, err = New(WithTableLayout(TableLayout(7)))
+	assert.ErrorIs(t, err, ccmap.ErrInvalidConfig)
+}
+
+// TestOpenAddressingBackground fills an open-addressing map faster than
+// its worker rehashes, with and without the writers helping it.
+func TestOpenAddressingBackground(t *testing.T) {
+	for _, help := range []bool{true, false} {
+		m, err := newHashMapConfig(16, mustConfig(t,
+			WithTableLayout(LayoutOpenAddressing),
+			WithRehashMode(RehashBackground),
+			WithRehashBudget(time.Nanosecond),
+		))
+		assert.NoError(t, err)
+		if !help {
+			// tables[1] fills up, puts must grow it.
+			m.maxRehashLoad = math.Inf(1)
+		}
+
+		for i := 0; i < 100000; i++ {
+			if !m.Put(NewStringKey(fmt.Sprintf("%d", i)), i) {
+				t.Fatalf("help %v: put %d failed", help, i)
+			}
+		}
+		assert.Equal(t, 100000, m.Len())
+		for i := 0; i < 100000; i += 7 {
+			actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+			assert.True(t, ok)
+			assert.Equal(t, i, actual)
+		}
+		m.Close()
+	}
+}
+
+func TestOpenAddressingHashMap(t *testing.T) {
+	m := newOpenMap(t, 16)
+
+	fill(m, 10000)
+	assert.Equal(t, 10000, m.Size())
+	assert.True(t, m.Stats().LoadFactor <= MAX_OPEN_LOAD_FACTOR)
+
+	for i := 0; i < 10000; i++ {
+		actual, ok := m.Get(NewStringKey(fmt.Sprintf("%d", i)))
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+	}
+
+	drain(m, 10000)
+	assert.Equal(t, 0, m.Size())
+	assert.Equal(t, 16, m.Stats().Buckets)
+	assert.True(t, m.Stats().Shrinks > 0)
+}
+
+// TestOpenAddressingGetWhileResizing is TestHashMapGetWhileResizing with
+// open addressing, run it with -race.
+func TestOpenAddressingGetWhileResizing(t *testing.T) {
+	m := newOpenMap(t, 8)
+	for i := 0; i < 100; i++ {
+		m.Put(NewStringKey(fmt.Sprintf("stable-%d", i)), i)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for r := 0; r < 20; r++ {
+			fill(m, 2000)
+			drain(m, 2000)
+		}
+		close(done)
+	}()
+
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				key := NewStringKey(fmt.Sprintf("stable-%d", i%100))
+				if actual, ok := m.Get(key); !ok || actual != i%100 {
+					t.Errorf("get %s: %v %v", key, actual, ok)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestOpenAddressingAllocs(t *testing.T) {
+	chained, _ := newHashMap(16384)
+	open := newOpenMap(t, 16384)
+
+	i := 0
+	allocs := func(m *hashMap) float64 {
+		return testing.AllocsPerRun(1000, func() {
+			m.Put(benchmarkKeys[i%len(benchmarkKeys)], i)
+			i++
+		})
+	}
+	assert.Less(t, allocs(open), allocs(chained))
+}
+
+func benchmarkLayoutPut(b *testing.B, layout TableLayout) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m, _ := New(WithSegments(1), WithTableLayout(layout))
+		for j, k := range benchmarkKeys {
+			m.Put(k, j)
+		}
+	}
+}
+
+func benchmarkLayoutGet(b *testing.B, layout TableLayout) {
+	m, _ := New(WithSegments(1), WithTableLayout(layout))
+	for j, k := range benchmarkKeys {
+		m.Put(k, j)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Get(benchmarkKeys[i%len(benchmarkKeys)])
+	}
+}
+
+func BenchmarkChainedPut(b *testing.B) {
+	benchmarkLayoutPut(b, LayoutChained)
+}
+
+func BenchmarkOpenAddressingPut(b *testing.B) {
+	benchmarkLayoutPut(b, LayoutOpenAddressing)
+}
+
+func BenchmarkChainedGet(b *testing.B) {
+	benchmarkLayoutGet(b, LayoutChained)
+}
+
+func BenchmarkOpenAddressingGet(b *testing.B) {
+	benchmarkLayoutGet(b, LayoutOpenAddressing)
+}
@@ -0,0 +1,266 @@
+package v1
+
+import (
+	"math/bits"
+
+	"github.com/csimplestring/go-concurrent-map/algo/hash"
+)
+
+const (
+	// sketchDepth is the number of rows of a count-min sketch.
+	sketchDepth = 4
+	// sketchSamples is the number of increments per counter of the sketch
+	// after which all the counters are halved.
+	sketchSamples = 10
+	// doorkeeperHashes is the number of bits set per key in the doorkeeper.
+	doorkeeperHashes = 3
+
+	// the window takes 1% of the entries of a W-TinyLFU policy, the
+	// protected segment 80% of the others.
+	windowPercent    = 1
+	protectedPercent = 80
+)
+
+// sketch is a count-min sketch of 4-bit counters estimating how often keys
+// were seen recently. Keys are only counted from their second occurrence:
+// the first one sets them in the doorkeeper, a bloom filter that keeps
+// one-hit wonders out of the counters. Once the sketch has counted
+// sketchSamples keys per counter, it ages: every counter is halved and
+// the doorkeeper is cleared, so old popularity fades away.
+type sketch struct {
+	// rows holds sketchDepth rows of width counters, 16 per word.
+	rows   [sketchDepth][]uint64
+	width  uint64
+	door   []uint64
+	adds   int
+	sample int
+}
+
+// newSketch creates a sketch sized for capacity keys.
+func newSketch(capacity int) *sketch {
+	width := uint64(16)
+	for width < uint64(capacity) {
+		width <<= 1
+	}
+
+	s := &sketch{
+		width:  width,
+		door:   make([]uint64, width/8),
+		sample: sketchSamples * int(width),
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint64, width/16)
+	}
+	return s
+}
+
+// index returns the i-th index below n derived from h by double hashing.
+func index(h uint64, i int, n uint64) uint64 {
+	return (h + uint64(i)*(h>>32|1)) & (n - 1)
+}
+
+// increment counts one occurrence of the key hashed to h.
+func (s *sketch) increment(h uint64) {
+	if !s.admit(h) {
+		return
+	}
+
+	added := false
+	for i := range s.rows {
+		j := index(h, i, s.width)
+		word, shift := &s.rows[i][j>>4], (j&15)*4
+		if (*word>>shift)&0xf < 0xf {
+			*word += 1 << shift
+			added = true
+		}
+	}
+	if added {
+		s.adds++
+		if s.adds >= s.sample {
+			s.age()
+		}
+	}
+}
+
+// estimate returns how many times the key hashed to h was seen, at most
+// 16.
+func (s *sketch) estimate(h uint64) int {
+	n := 0xf
+	for i := range s.rows {
+		j := index(h, i, s.width)
+		if c := int(s.rows[i][j>>4]>>((j&15)*4)) & 0xf; c < n {
+			n = c
+		}
+	}
+	if s.seen(h) {
+		n++
+	}
+	return n
+}
+
+// admit sets h in the doorkeeper. It returns true if h was already set.
+func (s *sketch) admit(h uint64) bool {
+	h = bits.RotateLeft64(h, 32)
+	n := uint64(len(s.door)) * 64
+	found := true
+	for i := 0; i < doorkeeperHashes; i++ {
+		j := index(h, i, n)
+		if s.door[j>>6]&(1<<(j&63)) == 0 {
+			s.door[j>>6] |= 1 << (j & 63)
+			found = false
+		}
+	}
+	return found
+}
+
+// seen returns true if h is set in the doorkeeper.
+func (s *sketch) seen(h uint64) bool {
+	h = bits.RotateLeft64(h, 32)
+	n := uint64(len(s.door)) * 64
+	for i := 0; i < doorkeeperHashes; i++ {
+		j := index(h, i, n)
+		if s.door[j>>6]&(1<<(j&63)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// age halves all the counters and clears the doorkeeper.
+func (s *sketch) age() {
+	for _, row := range s.rows {
+		for i := range row {
+			row[i] = (row[i] >> 1) & 0x7777777777777777
+		}
+	}
+	clear(s.door)
+	s.adds /= 2
+}
+
+// reset clears all the counters and the doorkeeper.
+func (s *sketch) reset() {
+	for _, row := range s.rows {
+		clear(row)
+	}
+	clear(s.door)
+	s.adds = 0
+}
+
+// the queues of a tinyLFUPolicy, see entry.queue.
+const (
+	queueWindow uint8 = iota
+	queueProbation
+	queueProtected
+)
+
+// tinyLFUPolicy is W-TinyLFU: new entries go through a small LRU window,
+// then compete to enter the main space, a segmented LRU. When the window
+// overflows, its least recently used entry is admitted into the main
+// space only if the sketch estimates it more frequently used than the
+// entry the main space would evict, otherwise it is evicted itself. The
+// main space keeps entries read while on probation in a protected LRU.
+type tinyLFUPolicy struct {
+	windowCap    int
+	mainCap      int
+	protectedCap int
+	window       lruList
+	probation    lruList
+	protected    lruList
+	sketch       *sketch
+}
+
+// newTinyLFUPolicy creates a tinyLFUPolicy keeping at most capacity
+// entries.
+func newTinyLFUPolicy(capacity int) *tinyLFUPolicy {
+	windowCap := max(1, capacity*windowPercent/100)
+	mainCap := capacity - windowCap
+	p := &tinyLFUPolicy{
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap * protectedPercent / 100,
+		sketch:       newSketch(capacity),
+	}
+	p.reset()
+	return p
+}
+
+// hashOf returns the hash of the key of e for the sketch.
+func (p *tinyLFUPolicy) hashOf(e *entry) uint64 {
+	return hash.Uint64(hash.DefaultSeed, uint64(e.k.Hash()))
+}
+
+// queue returns the list e is in.
+func (p *tinyLFUPolicy) queue(e *entry) *lruList {
+	switch e.queue {
+	case queueProbation:
+		return &p.probation
+	case queueProtected:
+		return &p.protected
+	}
+	return &p.window
+}
+
+func (p *tinyLFUPolicy) add(e *entry) *entry {
+	p.sketch.increment(p.hashOf(e))
+	e.queue = queueWindow
+	p.window.pushFront(e)
+	if p.window.len <= p.windowCap {
+		return nil
+	}
+
+	candidate := p.window.back()
+	p.window.remove(candidate)
+	candidate.queue = queueProbation
+	p.probation.pushFront(candidate)
+	if p.probation.len+p.protected.len <= p.mainCap {
+		return nil
+	}
+
+	victim := p.probation.back()
+	if victim == candidate {
+		if victim = p.protected.back(); victim == nil {
+			return candidate
+		}
+	}
+	if p.sketch.estimate(p.hashOf(candidate)) > p.sketch.estimate(p.hashOf(victim)) {
+		return victim
+	}
+	return candidate
+}
+
+func (p *tinyLFUPolicy) access(e *entry) {
+	p.sketch.increment(p.hashOf(e))
+	switch e.queue {
+	case queueWindow:
+		p.window.moveToFront(e)
+	case queueProbation:
+		p.probation.remove(e)
+		e.queue = queueProtected
+		p.protected.pushFront(e)
+		if p.protected.len > p.protectedCap {
+			demoted := p.protected.back()
+			p.protected.remove(demoted)
+			demoted.queue = queueProbation
+			p.probation.pushFront(demoted)
+		}
+	case queueProtected:
+		p.protected.moveToFront(e)
+	}
+}
+
+func (p *tinyLFUPolicy) replace(old, new *entry) {
+	new.queue = old.queue
+	p.queue(old).replace(old, new)
+	p.access(new)
+}
+
+func (p *tinyLFUPolicy) remove(e *entry) {
+	p.queue(e).remove(e)
+}
+
+func (p *tinyLFUPolicy) reset() {
+	p.window.init()
+	p.probation.init()
+	p.protected.init()
+	p.sketch.reset()
+}
@@ -0,0 +1,269 @@
+package v1
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/csimplestring/go-concurrent-map/ccmap/key"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSketch(t *testing.T) {
+	s := newSketch(64)
+
+	// the first occurrence only sets the doorkeeper.
+	s.increment(1)
+	assert.Equal(t, 1, s.estimate(1))
+	assert.Equal(t, 0, s.estimate(2))
+
+	for i := 0; i < 5; i++ {
+		s.increment(1)
+	}
+	assert.Equal(t, 6, s.estimate(1))
+
+	// counters saturate at 15, plus one for the doorkeeper.
+	for i := 0; i < 100; i++ {
+		s.increment(1)
+	}
+	assert.Equal(t, 16, s.estimate(1))
+
+	s.reset()
+	assert.Equal(t, 0, s.estimate(1))
+}
+
+func TestSketchAging(t *testing.T) {
+	s := newSketch(16)
+	for i := 0; i < 9; i++ {
+		s.increment(1)
+	}
+	assert.Equal(t, 9, s.estimate(1))
+
+	s.age()
+	assert.Equal(t, 4, s.estimate(1))
+	assert.Equal(t, 4, s.adds)
+
+	// the sketch ages by itself once it counted sample keys.
+	s.increment(1)
+	s.increment(1)
+	assert.Equal(t, 6, s.estimate(1))
+	s.adds = s.sample - 1
+	s.increment(1)
+	assert.Equal(t, 3, s.estimate(1))
+	assert.Equal(t, s.sample/2, s.adds)
+}
+
+func TestTinyLFUAdmission(t *testing.T) {
+	p := newTinyLFUPolicy(100)
+	assert.Equal(t, 1, p.windowCap)
+	assert.Equal(t, 99, p.mainCap)
+	assert.Equal(t, 79, p.protectedCap)
+
+	popular := make([]*entry, 99)
+	for i := range popular {
+		popular[i] = &entry{k: NewIntKey(i)}
+		assert.Nil(t, p.add(popular[i]))
+	}
+	for _, e := range popular {
+		p.access(e)
+		p.access(e)
+	}
+
+	// a key seen once is not admitted in place of a popular one.
+	e := &entry{k: NewIntKey(1000)}
+	assert.Nil(t, p.add(e))
+	once := &entry{k: NewIntKey(1001)}
+	assert.True(t, e == p.add(once))
+	p.remove(e)
+	assert.Equal(t, 100, p.window.len+p.probation.len+p.protected.len)
+}
+
+func TestTinyLFUSmallCapacity(t *testing.T) {
+	p := newTinyLFUPolicy(1)
+	e1, e2 := &entry{k: NewIntKey(1)}, &entry{k: NewIntKey(2)}
+	assert.Nil(t, p.add(e1))
+	victim := p.add(e2)
+	assert.NotNil(t, victim)
+	p.remove(victim)
+	assert.Equal(t, 1, p.window.len+p.probation.len+p.protected.len)
+}
+
+// checkTinyLFU checks that the lists of the policy of every segment of m
+// hold the entries of the segment.
+func checkTinyLFU(t *testing.T, m ConcurrentMap) {
+	for _, s := range m.(*concurrentHashMap).load().segments {
+		s.mutex.RLock()
+		p := s.policy.(*tinyLFUPolicy)
+		n := 0
+		for q, l := range []*lruList{&p.window, &p.probation, &p.protected} {
+			for e := l.root.next; e != &l.root; e = e.next {
+				assert.Equal(t, uint8(q), e.queue)
+				en, ok := s.find(e.k)
+				assert.True(t, ok)
+				assert.True(t, Entry(e) == en)
+				n++
+			}
+		}
+		assert.Equal(t, s.Len(), n)
+		assert.True(t, p.protected.len <= p.protectedCap)
+		s.mutex.RUnlock()
+	}
+}
+
+func TestMaxEntriesTinyLFU(t *testing.T) {
+	var ev evictions
+	c, _ := New(WithSegments(4), WithMaxEntries(100), WithEvictionPolicy(PolicyTinyLFU),
+		WithEvictionListener(ev.onEvict))
+
+	fillMap(c, 1000)
+	for i := 0; i < 1000; i += 3 {
+		c.Get(NewIntKey(i))
+		c.Put(NewIntKey(i), -i)
+	}
+	c.Delete(NewIntKey(0))
+
+	assert.True(t, c.Len() <= 100)
+	assert.Equal(t, int64(len(ev.list)), c.Stats().Evictions)
+	checkTinyLFU(t, c)
+
+	c.Clear()
+	checkTinyLFU(t, c)
+}
+
+// zipfTrace returns n reads of keys keys whose popularity follows a Zipf
+// law of exponent s, the same for a given seed.
+func zipfTrace(seed uint64, n, keys int, s float64) []Key {
+	z := rand.NewZipf(rand.New(rand.NewPCG(seed, 0)), s, 1, uint64(keys-1))
+	trace := make([]Key, n)
+	for i := range trace {
+		trace[i] = NewStringKey(fmt.Sprintf("k%d", z.Uint64()))
+	}
+	return trace
+}
+
+// scanTrace returns the reads of zipfTrace interrupted every every reads
+// by a scan of scan keys read once.
+func scanTrace(seed uint64, n, keys int, s float64, every, scan int) []Key {
+	var trace []Key
+	for i, k := range zipfTrace(seed, n, keys, s) {
+		if i > 0 && i%every == 0 {
+			for j := 0; j < scan; j++ {
+				trace = append(trace, NewStringKey(fmt.Sprintf("scan%d-%d", i, j)))
+			}
+		}
+		trace = append(trace, k)
+	}
+	return trace
+}
+
+// replay reads keys from a map bounded to capacity entries, putting the
+// missing ones like a cache would, and returns its hit ratio.
+func replay(t *testing.T, keys []Key, capacity int, policy EvictionPolicy) float64 {
+	c, err := New(WithMaxEntries(capacity), WithEvictionPolicy(policy), WithSeed(42))
+	assert.NoError(t, err)
+
+	for _, k := range keys {
+		if _, ok := c.Get(k); !ok {
+			c.Put(k, true)
+		}
+	}
+	return c.Stats().HitRatio()
+}
+
+// traceCapacities are the capacities the traces are replayed with.
+var traceCapacities = []int{250, 500, 1000}
+
+// compareHitRatio replays keys with LRU and W-TinyLFU at every capacity of
+// traceCapacities and logs both hit ratios. If strict, W-TinyLFU must
+// beat LRU.
+func compareHitRatio(t *testing.T, name string, keys []Key, strict bool) {
+	for _, capacity := range traceCapacities {
+		lru := replay(t, keys, capacity, PolicyLRU)
+		lfu := replay(t, keys, capacity, PolicyTinyLFU)
+		t.Logf("%-10s capacity %5d: LRU %.4f, W-TinyLFU %.4f",
+			name, capacity, lru, lfu)
+		if strict {
+			assert.True(t, lfu > lru, "%s capacity %d: W-TinyLFU %.4f <= LRU %.4f",
+				name, capacity, lfu, lru)
+		}
+	}
+}
+
+// TestTraceHitRatio replays generated traces and compares the hit ratio
+// of W-TinyLFU with the one of LRU. Run it with -v for the report.
+func TestTraceHitRatio(t *testing.T) {
+	compareHitRatio(t, "zipf", zipfTrace(1, 30000, 10000, 1.01), true)
+	compareHitRatio(t, "zipf+scan", scanTrace(2, 30000, 2000, 1.01, 4000, 1500), true)
+}
+
+var accessLog = flag.String("accesslog", "",
+	"recorded access log replayed by TestRecordedTraceHitRatio, see readTrace")
+
+// readTrace reads a recorded access log, one key per line. Blank lines
+// and lines starting with # are skipped.
+func readTrace(path string) ([]Key, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var keys []Key
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, NewStringKey(line))
+	}
+	return keys, scanner.Err()
+}
+
+func TestReadTrace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.trace")
+	assert.NoError(t, os.WriteFile(path, []byte("# a comment\nk1\n\n  k2 \nk1\n"), 0o644))
+
+	keys, err := readTrace(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []Key{NewStringKey("k1"), NewStringKey("k2"), NewStringKey("k1")}, keys)
+
+	_, err = readTrace(filepath.Join(t.TempDir(), "absent.trace"))
+	assert.Error(t, err)
+}
+
+// TestRecordedTraceHitRatio replays the recorded access log given by
+// -accesslog, or those of testdata/*.trace, and reports the hit ratio of
+// W-TinyLFU and LRU. It is skipped when there is no trace. Run it with -v
+// for the report, e.g.
+//
+//	go test -run TestRecordedTraceHitRatio -v -accesslog access.log
+func TestRecordedTraceHitRatio(t *testing.T) {
+	paths := []string{*accessLog}
+	if *accessLog == "" {
+		var err error
+		paths, err = filepath.Glob(filepath.Join("testdata", "*.trace"))
+		assert.NoError(t, err)
+	}
+	if len(paths) == 0 {
+		t.Skip("no recorded trace, pass one with -accesslog or put it in testdata/*.trace")
+	}
+
+	for _, path := range paths {
+		keys, err := readTrace(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compareHitRatio(t, filepath.Base(path), keys, false)
+	}
+}
+
+func BenchmarkCCHashMapGetTinyLFU(b *testing.B) {
+	m, _ := New(WithMaxEntries(len(benchmarkKeys)), WithEvictionPolicy(PolicyTinyLFU))
+	benchmarkReadHeavy(b, m)
+}
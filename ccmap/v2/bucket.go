@@ -0,0 +1,101 @@
+package v2
+
+const (
+	entryErr     = 0
+	entryAdd     = 1
+	entryReplace = 2
+)
+
+// bucket is a singly linked list of entries.
+type bucket[K comparable, V any] struct {
+	cnt  int
+	head *entry[K, V]
+}
+
+// push appends en at the end of b.
+func (b *bucket[K, V]) push(en *entry[K, V]) bool {
+	en.next = nil
+	if b.head == nil {
+		b.head = en
+	} else {
+		tail := b.head
+		for tail.next != nil {
+			tail = tail.next
+		}
+		tail.next = en
+	}
+	b.cnt++
+	return true
+}
+
+// put replaces the value of the entry with the same key as en,
+// or inserts en at the beginning of b.
+func (b *bucket[K, V]) put(en *entry[K, V]) int {
+	for current := b.head; current != nil; current = current.next {
+		if current.hash == en.hash && current.key == en.key {
+			current.value = en.value
+			return entryReplace
+		}
+	}
+
+	en.next = b.head
+	b.head = en
+	b.cnt++
+	return entryAdd
+}
+
+// get finds entry based on key.
+func (b *bucket[K, V]) get(hash int, key K) (*entry[K, V], bool) {
+	for current := b.head; current != nil; current = current.next {
+		if current.hash == hash && current.key == key {
+			return current, true
+		}
+	}
+	return nil, false
+}
+
+// delete deletes the entry based on key.
+// Returns the deleted entry and the number of deleted entries.
+func (b *bucket[K, V]) delete(hash int, key K) (*entry[K, V], int) {
+	var prev *entry[K, V]
+	for current := b.head; current != nil; current = current.next {
+		if current.hash == hash && current.key == key {
+			if prev == nil {
+				b.head = current.next
+			} else {
+				prev.next = current.next
+			}
+			b.cnt--
+			return current, 1
+		}
+		prev = current
+	}
+	return nil, 0
+}
+
+// pop pops the first entry. Returns false if no entry in b.
+func (b *bucket[K, V]) pop() (*entry[K, V], bool) {
+	first := b.head
+	if first == nil {
+		return nil, false
+	}
+	b.head = first.next
+	first.next = nil
+	b.cnt--
+	return first, true
+}
+
+// size returns the number of entry in b.
+func (b *bucket[K, V]) size() int {
+	return b.cnt
+}
+
+// String returns a string representation of b.
+func (b *bucket[K, V]) String() string {
+	str := "["
+	for current := b.head; current != nil; current = current.next {
+		str += current.String() + ","
+	}
+	str += "]"
+	return str
+}
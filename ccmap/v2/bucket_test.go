@@ -0,0 +1,82 @@
+package v2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newStringEntry(k string, v int) *entry[string, int] {
+	return newEntry(DefaultHasher[string]()(k), k, v)
+}
+
+func TestBucketPush(t *testing.T) {
+	b := &bucket[string, int]{}
+
+	b.push(newStringEntry("k1", 1))
+	assert.Equal(t, "[[k1 1],]", b.String())
+
+	b.push(newStringEntry("k2", 2))
+	assert.Equal(t, "[[k1 1],[k2 2],]", b.String())
+
+	b.push(newStringEntry("k3", 3))
+	assert.Equal(t, "[[k1 1],[k2 2],[k3 3],]", b.String())
+}
+
+func TestBucketPut(t *testing.T) {
+	b := &bucket[string, int]{}
+
+	assert.Equal(t, entryAdd, b.put(newStringEntry("k1", 1)))
+	assert.Equal(t, entryAdd, b.put(newStringEntry("k2", 2)))
+	assert.Equal(t, entryReplace, b.put(newStringEntry("k2", 7)))
+
+	assert.Equal(t, "[[k2 7],[k1 1],]", b.String())
+	assert.Equal(t, 2, b.size())
+}
+
+func TestBucketGet(t *testing.T) {
+	b := &bucket[string, int]{}
+	b.put(newStringEntry("k1", 1))
+	b.put(newStringEntry("k2", 2))
+	b.put(newStringEntry("k3", 3))
+
+	k2 := newStringEntry("k2", 0)
+	en, ok := b.get(k2.hash, k2.key)
+	assert.True(t, ok)
+	assert.Equal(t, 2, en.value)
+
+	k4 := newStringEntry("k4", 0)
+	en, ok = b.get(k4.hash, k4.key)
+	assert.False(t, ok)
+	assert.Nil(t, en)
+}
+
+func TestBucketDelete(t *testing.T) {
+	b := &bucket[string, int]{}
+	b.put(newStringEntry("k1", 1))
+	b.put(newStringEntry("k2", 2))
+	b.put(newStringEntry("k3", 3))
+
+	k2 := newStringEntry("k2", 0)
+	en, cnt := b.delete(k2.hash, k2.key)
+	assert.Equal(t, 1, cnt)
+	assert.Equal(t, "[k2 2]", en.String())
+	assert.Equal(t, "[[k3 3],[k1 1],]", b.String())
+
+	en, cnt = b.delete(k2.hash, k2.key)
+	assert.Equal(t, 0, cnt)
+	assert.Nil(t, en)
+}
+
+func TestBucketPop(t *testing.T) {
+	b := &bucket[string, int]{}
+	b.put(newStringEntry("k1", 1))
+
+	en, ok := b.pop()
+	assert.True(t, ok)
+	assert.Equal(t, "[k1 1]", en.String())
+	assert.Equal(t, "[]", b.String())
+
+	_, ok = b.pop()
+	assert.False(t, ok)
+}
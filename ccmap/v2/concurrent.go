@@ -0,0 +1,81 @@
+package v2
+
+const (
+	MAX_SEGMENTS = 65536
+)
+
+// concurrentHashMap splits keys over segments, each segment is a hashMap
+// with its own lock.
+type concurrentHashMap[K comparable, V any] struct {
+	segmentShift uint
+	segmentMask  int
+	segments     []*hashMap[K, V]
+	hasher       Hasher[K]
+}
+
+// NewConcurrentMap creates a segmented Map, keys are hashed by
+// DefaultHasher.
+func NewConcurrentMap[K comparable, V any](concurrencyLevel int) (Map[K, V], error) {
+	return newConcurrentMap[K, V](concurrencyLevel, DefaultHasher[K]())
+}
+
+// NewConcurrentMapWithHasher is like NewConcurrentMap but hashes keys
+// with hasher.
+func NewConcurrentMapWithHasher[K comparable, V any](concurrencyLevel int, hasher Hasher[K]) (Map[K, V], error) {
+	return newConcurrentMap[K, V](concurrencyLevel, hasher)
+}
+
+func newConcurrentMap[K comparable, V any](concurrencyLevel int, hasher Hasher[K]) (*concurrentHashMap[K, V], error) {
+	if concurrencyLevel > MAX_SEGMENTS {
+		concurrencyLevel = MAX_SEGMENTS
+	}
+
+	sshift := 0
+	ssize := 1
+	for ssize < concurrencyLevel {
+		sshift = sshift + 1
+		ssize = ssize << 1
+	}
+
+	var err error
+	segments := make([]*hashMap[K, V], ssize)
+	for i := 0; i < ssize; i++ {
+		segments[i], err = newHashMap[K, V](16, hasher)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &concurrentHashMap[K, V]{
+		segmentMask:  ssize - 1,
+		segmentShift: (uint)(32 - sshift),
+		segments:     segments,
+		hasher:       hasher,
+	}, nil
+}
+
+func (c *concurrentHashMap[K, V]) hash(h int) int {
+	h += (h << 15) ^ 0xffffcd7d
+	h ^= (h >> 10)
+	h += (h << 3)
+	h ^= (h >> 6)
+	h += (h << 2) + (h << 14)
+	return h ^ (h >> 16)
+}
+
+func (c *concurrentHashMap[K, V]) segmentFor(key K) *hashMap[K, V] {
+	hash := c.hash(c.hasher(key))
+	return c.segments[(hash>>c.segmentShift)&c.segmentMask]
+}
+
+func (c *concurrentHashMap[K, V]) Put(key K, val V) bool {
+	return c.segmentFor(key).Put(key, val)
+}
+
+func (c *concurrentHashMap[K, V]) Get(key K) (V, bool) {
+	return c.segmentFor(key).Get(key)
+}
+
+func (c *concurrentHashMap[K, V]) Delete(key K) bool {
+	return c.segmentFor(key).Delete(key)
+}
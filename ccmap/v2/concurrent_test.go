@@ -0,0 +1,101 @@
+package v2
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/csimplestring/go-concurrent-map/algo/random"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	benchmarkKeys []string
+)
+
+func init() {
+	benchmarkKeys = make([]string, 10000)
+	for i := 0; i < 10000; i++ {
+		benchmarkKeys[i] = random.NewLen(15)
+	}
+}
+
+func TestNewConcurrentMap(t *testing.T) {
+	m, err := NewConcurrentMap[string, int](8)
+	assert.Nil(t, err)
+	assert.Equal(t, 8, len(m.(*concurrentHashMap[string, int]).segments))
+}
+
+func TestCCHashMapGetOK(t *testing.T) {
+	m, _ := NewConcurrentMap[int, string](8)
+
+	for i := 0; i < 1000; i++ {
+		m.Put(i, fmt.Sprintf("%d", i))
+	}
+
+	for i := 0; i < 1000; i++ {
+		actual, ok := m.Get(i)
+		assert.True(t, ok)
+		assert.Equal(t, fmt.Sprintf("%d", i), actual)
+	}
+
+	for i := 1000; i < 1100; i++ {
+		actual, ok := m.Get(i)
+		assert.False(t, ok)
+		assert.Equal(t, "", actual)
+	}
+}
+
+func TestCCHashMapDelete(t *testing.T) {
+	m, _ := NewConcurrentMap[int, int](8)
+
+	for i := 0; i < 1000; i++ {
+		m.Put(i, i)
+	}
+
+	for i := 0; i < 1000; i += 2 {
+		assert.True(t, m.Delete(i), "key %d", i)
+		assert.False(t, m.Delete(i), "key %d", i)
+	}
+
+	for i := 0; i < 1000; i++ {
+		_, ok := m.Get(i)
+		assert.Equal(t, i%2 == 1, ok, "key %d", i)
+	}
+}
+
+func TestCCHashMapParallel(t *testing.T) {
+	m, _ := NewConcurrentMap[string, int](4)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				k := fmt.Sprintf("%d-%d", g, i)
+				m.Put(k, i)
+				if v, ok := m.Get(k); !ok || v != i {
+					t.Errorf("get %s: %d %v", k, v, ok)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for g := 0; g < 8; g++ {
+		for i := 0; i < 500; i++ {
+			v, ok := m.Get(fmt.Sprintf("%d-%d", g, i))
+			assert.True(t, ok)
+			assert.Equal(t, i, v)
+		}
+	}
+}
+
+func BenchmarkCCHashMapPut(b *testing.B) {
+	m, _ := NewConcurrentMap[string, int](8)
+
+	for i := 0; i < b.N; i++ {
+		m.Put(benchmarkKeys[i%len(benchmarkKeys)], i)
+	}
+}
@@ -0,0 +1,26 @@
+package v2
+
+import "fmt"
+
+// entry stores a <key, value> pair together with the hash of key and
+// links to the next entry in the same bucket.
+type entry[K comparable, V any] struct {
+	hash  int
+	key   K
+	value V
+	next  *entry[K, V]
+}
+
+// newEntry creates a new entry.
+func newEntry[K comparable, V any](hash int, k K, v V) *entry[K, V] {
+	return &entry[K, V]{
+		hash:  hash,
+		key:   k,
+		value: v,
+	}
+}
+
+// String returns a string representation of e.
+func (e *entry[K, V]) String() string {
+	return fmt.Sprintf("[%v %v]", e.key, e.value)
+}
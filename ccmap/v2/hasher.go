@@ -0,0 +1,64 @@
+package v2
+
+import (
+	"hash/maphash"
+	"unsafe"
+)
+
+// Hasher computes the hash code of a key.
+type Hasher[K comparable] func(K) int
+
+// seed is shared by all the default hashers of this process.
+var seed = maphash.MakeSeed()
+
+// DefaultHasher returns the Hasher used for K when none is given.
+// Strings and integers get a specialised hasher, any other comparable
+// type is hashed with maphash.Comparable.
+func DefaultHasher[K comparable]() Hasher[K] {
+	var zero K
+	switch any(zero).(type) {
+	case string:
+		return func(k K) int {
+			return int(maphash.String(seed, *(*string)(unsafe.Pointer(&k))))
+		}
+	case int, uint:
+		return func(k K) int {
+			return int(mix64(uint64(*(*uint)(unsafe.Pointer(&k)))))
+		}
+	case uintptr:
+		return func(k K) int {
+			return int(mix64(uint64(*(*uintptr)(unsafe.Pointer(&k)))))
+		}
+	case int64, uint64:
+		return func(k K) int {
+			return int(mix64(*(*uint64)(unsafe.Pointer(&k))))
+		}
+	case int32, uint32:
+		return func(k K) int {
+			return int(mix64(uint64(*(*uint32)(unsafe.Pointer(&k)))))
+		}
+	case int16, uint16:
+		return func(k K) int {
+			return int(mix64(uint64(*(*uint16)(unsafe.Pointer(&k)))))
+		}
+	case int8, uint8:
+		return func(k K) int {
+			return int(mix64(uint64(*(*uint8)(unsafe.Pointer(&k)))))
+		}
+	}
+
+	return func(k K) int {
+		return int(maphash.Comparable(seed, k))
+	}
+}
+
+// mix64 is the finalizer of splitmix64, it spreads every input bit
+// over the whole word.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
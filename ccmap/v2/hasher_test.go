@@ -0,0 +1,42 @@
+package v2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type point struct {
+	x, y int
+}
+
+func TestDefaultHasherString(t *testing.T) {
+	h := DefaultHasher[string]()
+	assert.Equal(t, h("cat"), h("cat"))
+	assert.NotEqual(t, h("cat"), h("dog"))
+}
+
+func TestDefaultHasherInt(t *testing.T) {
+	h := DefaultHasher[int]()
+	assert.Equal(t, h(42), h(42))
+	assert.NotEqual(t, h(1), h(2))
+
+	h8 := DefaultHasher[uint8]()
+	assert.NotEqual(t, h8(1), h8(2))
+}
+
+func TestDefaultHasherStruct(t *testing.T) {
+	h := DefaultHasher[point]()
+	assert.Equal(t, h(point{1, 2}), h(point{1, 2}))
+	assert.NotEqual(t, h(point{1, 2}), h(point{2, 1}))
+}
+
+func TestDefaultHasherSpreadsLowBits(t *testing.T) {
+	h := DefaultHasher[int]()
+
+	buckets := make(map[int]int)
+	for i := 0; i < 1024; i++ {
+		buckets[h(i<<10)&15]++
+	}
+	assert.Equal(t, 16, len(buckets))
+}
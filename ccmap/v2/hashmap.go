@@ -0,0 +1,177 @@
+package v2
+
+import "sync"
+
+// hashMap is the default implementation of Map.
+type hashMap[K comparable, V any] struct {
+	// -1: no rehash; otherwise it is rehashing
+	rehashIdx int
+	entryCnt  int
+	tables    [2]*htable[K, V]
+	hasher    Hasher[K]
+	mutex     sync.RWMutex
+}
+
+// NewHashMap creates a Map guarded by a single lock, keys are hashed by
+// DefaultHasher.
+func NewHashMap[K comparable, V any](size int) (Map[K, V], error) {
+	return newHashMap[K, V](size, DefaultHasher[K]())
+}
+
+// NewHashMapWithHasher is like NewHashMap but hashes keys with hasher.
+func NewHashMapWithHasher[K comparable, V any](size int, hasher Hasher[K]) (Map[K, V], error) {
+	return newHashMap[K, V](size, hasher)
+}
+
+func newHashMap[K comparable, V any](size int, hasher Hasher[K]) (*hashMap[K, V], error) {
+	table, err := newHtable[K, V](size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hashMap[K, V]{
+		entryCnt:  0,
+		tables:    [2]*htable[K, V]{table, nil},
+		hasher:    hasher,
+		rehashIdx: -1,
+	}, nil
+}
+
+// Put puts <key, val> pair in correct slot.
+// It returns true if succeed; otherwise false.
+func (h *hashMap[K, V]) Put(key K, val V) bool {
+	hash := h.hasher(key)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !h.isRehashing() {
+		if ok := h.putEntry(0, newEntry(hash, key, val)); !ok {
+			return false
+		}
+
+		if h.entryCnt > len(h.tables[0].buckets) {
+			h.beginRehash()
+		}
+
+		return true
+	}
+
+	// the key may not have been moved yet.
+	if en, ok := h.tables[0].get(hash, key); ok {
+		en.value = val
+	} else if ok := h.putEntry(1, newEntry(hash, key, val)); !ok {
+		return false
+	}
+
+	h.rehash()
+	return true
+}
+
+// Get gets the value based on key.
+// If value exists, it returns value and TRUE;
+// otherwise it returns zero value and FALSE.
+func (h *hashMap[K, V]) Get(key K) (V, bool) {
+	hash := h.hasher(key)
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	if h.isRehashing() {
+		if en, ok := h.tables[1].get(hash, key); ok {
+			return en.value, true
+		}
+	}
+
+	if en, ok := h.tables[0].get(hash, key); ok {
+		return en.value, true
+	}
+
+	var zero V
+	return zero, false
+}
+
+// Delete deletes value based on key.
+// It returns TRUE if key exists; otherise FALSE.
+func (h *hashMap[K, V]) Delete(key K) bool {
+	hash := h.hasher(key)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	_, deleted := h.tables[0].delete(hash, key)
+	if h.isRehashing() {
+		_, cnt := h.tables[1].delete(hash, key)
+		deleted += cnt
+		h.rehash()
+	}
+
+	h.entryCnt -= deleted
+	return deleted > 0
+}
+
+// Size returns number of entries.
+func (h *hashMap[K, V]) Size() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.entryCnt
+}
+
+// putEntry puts en into tables[tableIdx].
+// It returns true if succeeds, otherwise false.
+func (h *hashMap[K, V]) putEntry(tableIdx int, en *entry[K, V]) bool {
+	status := h.tables[tableIdx].put(en)
+
+	if status == entryAdd {
+		h.entryCnt++
+	}
+
+	return status != entryErr
+}
+
+/*********** Expand Hash ***********/
+
+func (h *hashMap[K, V]) isRehashing() bool {
+	return h.rehashIdx != -1
+}
+
+// beginRehash sets rehashIdx to be 0, creates new htable for
+// tables[1].
+func (h *hashMap[K, V]) beginRehash() {
+	table, err := newHtable[K, V](len(h.tables[0].buckets) * 2)
+	if err != nil {
+		return
+	}
+	h.tables[1] = table
+	h.rehashIdx = 0
+}
+
+// stopRehash switches old and new htable internally, resets
+// rehashIdx to be -1.
+func (h *hashMap[K, V]) stopRehash() {
+	h.tables[0] = h.tables[1]
+	h.tables[1] = nil
+	h.rehashIdx = -1
+}
+
+// rehash moves the next non-empty bucket of tables[0] to tables[1].
+func (h *hashMap[K, V]) rehash() {
+	old := h.tables[0]
+	for h.rehashIdx < len(old.buckets) && old.buckets[h.rehashIdx].size() == 0 {
+		h.rehashIdx++
+	}
+
+	if h.rehashIdx < len(old.buckets) {
+		b := &old.buckets[h.rehashIdx]
+		for en, ok := b.pop(); ok; en, ok = b.pop() {
+			h.tables[1].push(en)
+		}
+		h.rehashIdx++
+	}
+
+	// rehash ends
+	if h.rehashIdx == len(old.buckets) {
+		h.stopRehash()
+	}
+}
@@ -0,0 +1,144 @@
+package v2
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashMapSize(t *testing.T) {
+	m, _ := newHashMap[string, int](6, DefaultHasher[string]())
+
+	m.Put("1", 1)
+	m.Put("2", 1)
+	m.Put("3", 1)
+	assert.Equal(t, 3, m.Size())
+
+	m.Put("3", 3)
+	assert.Equal(t, 3, m.Size())
+
+	m.Delete("2")
+	assert.Equal(t, 2, m.Size())
+
+	m.Delete("2")
+	assert.Equal(t, 2, m.Size())
+}
+
+func TestHashMapGetOK(t *testing.T) {
+	m, _ := NewHashMap[string, int](4)
+
+	for i := 0; i < 100; i++ {
+		m.Put(fmt.Sprintf("%d", i), i)
+	}
+
+	for i := 0; i < 100; i++ {
+		actual, ok := m.Get(fmt.Sprintf("%d", i))
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+	}
+
+	for i := 0; i < 100; i++ {
+		m.Put(fmt.Sprintf("%d", i), i*2)
+	}
+
+	for i := 0; i < 100; i++ {
+		actual, ok := m.Get(fmt.Sprintf("%d", i))
+		assert.True(t, ok)
+		assert.Equal(t, i*2, actual)
+	}
+	assert.Equal(t, 100, m.(*hashMap[string, int]).Size())
+}
+
+func TestHashMapGetFail(t *testing.T) {
+	m, _ := NewHashMap[string, int](100)
+
+	for i := 0; i < 30; i++ {
+		m.Put(fmt.Sprintf("%d", i), i)
+	}
+
+	for i := 31; i < 60; i++ {
+		actual, ok := m.Get(fmt.Sprintf("%d", i))
+		assert.False(t, ok)
+		assert.Equal(t, 0, actual)
+	}
+}
+
+func TestHashMapDeleteOK(t *testing.T) {
+	m, _ := NewHashMap[string, int](4)
+
+	for i := 0; i < 100; i++ {
+		m.Put(fmt.Sprintf("%d", i), i)
+	}
+
+	for i := 0; i < 100; i++ {
+		assert.True(t, m.Delete(fmt.Sprintf("%d", i)), "key %d", i)
+	}
+
+	for i := 0; i < 100; i++ {
+		_, ok := m.Get(fmt.Sprintf("%d", i))
+		assert.False(t, ok)
+	}
+}
+
+func TestHashMapDeleteFail(t *testing.T) {
+	m, _ := NewHashMap[string, int](100)
+
+	for i := 0; i < 30; i++ {
+		m.Put(fmt.Sprintf("%d", i), i)
+	}
+
+	for i := 31; i < 60; i++ {
+		assert.False(t, m.Delete(fmt.Sprintf("%d", i)))
+	}
+}
+
+func TestHashMapStructKey(t *testing.T) {
+	m, _ := NewHashMap[point, string](16)
+
+	m.Put(point{1, 2}, "a")
+	m.Put(point{2, 1}, "b")
+
+	actual, ok := m.Get(point{1, 2})
+	assert.True(t, ok)
+	assert.Equal(t, "a", actual)
+
+	actual, ok = m.Get(point{2, 1})
+	assert.True(t, ok)
+	assert.Equal(t, "b", actual)
+}
+
+func TestHashMapWithHasher(t *testing.T) {
+	// every key collides, so all of them share one bucket.
+	m, _ := NewHashMapWithHasher[int, int](16, func(int) int { return 7 })
+
+	for i := 0; i < 10; i++ {
+		m.Put(i, i)
+	}
+	for i := 0; i < 10; i++ {
+		actual, ok := m.Get(i)
+		assert.True(t, ok)
+		assert.Equal(t, i, actual)
+	}
+}
+
+func BenchmarkHashMapPut(b *testing.B) {
+	m, _ := NewHashMap[string, int](100)
+
+	for i := 0; i < b.N; i++ {
+		m.Put(benchmarkKeys[i%len(benchmarkKeys)], i)
+	}
+}
+
+func BenchmarkHashMapGet(b *testing.B) {
+	m, _ := NewHashMap[string, int](100)
+
+	for i, k := range benchmarkKeys {
+		m.Put(k, i)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		m.Get(benchmarkKeys[i%len(benchmarkKeys)])
+	}
+}
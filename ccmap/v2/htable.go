@@ -0,0 +1,63 @@
+package v2
+
+import "fmt"
+
+// htable is the underlying hash tables. It stores
+// <key, value> pairs in buckets.
+type htable[K comparable, V any] struct {
+	mask    int
+	buckets []bucket[K, V]
+}
+
+// newHtable creates a new empty htable with specified size.
+// Note that size is rounded up to 2^n.
+func newHtable[K comparable, V any](size int) (*htable[K, V], error) {
+	if size < 0 {
+		return nil,
+			fmt.Errorf("Illegal arg: %d, size of tables should be positive.", size)
+	}
+
+	n := 1
+	for n < size {
+		n = n << 1
+	}
+
+	return &htable[K, V]{
+		mask:    n - 1,
+		buckets: make([]bucket[K, V], n),
+	}, nil
+}
+
+// indexFor gives index of bucket for hash. It equals MOD operator.
+func (ht *htable[K, V]) indexFor(hash int) int {
+	return hash & ht.mask
+}
+
+// get gets entry based on key.
+func (ht *htable[K, V]) get(hash int, key K) (*entry[K, V], bool) {
+	return ht.buckets[ht.indexFor(hash)].get(hash, key)
+}
+
+// put puts en at the beginning of bucket.
+func (ht *htable[K, V]) put(en *entry[K, V]) int {
+	return ht.buckets[ht.indexFor(en.hash)].put(en)
+}
+
+// delete deletes value based on key.
+func (ht *htable[K, V]) delete(hash int, key K) (*entry[K, V], int) {
+	return ht.buckets[ht.indexFor(hash)].delete(hash, key)
+}
+
+// push inserts en at the end of bucket.
+func (ht *htable[K, V]) push(en *entry[K, V]) bool {
+	return ht.buckets[ht.indexFor(en.hash)].push(en)
+}
+
+// size returns the number of entries in the buckets.
+func (ht *htable[K, V]) size() int {
+	cnt := 0
+	for i := range ht.buckets {
+		cnt += ht.buckets[i].size()
+	}
+	return cnt
+}
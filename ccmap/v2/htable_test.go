@@ -0,0 +1,33 @@
+package v2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHtableOk(t *testing.T) {
+	h, err := newHtable[string, int](3)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(h.buckets))
+
+	h, _ = newHtable[string, int](15)
+	assert.Equal(t, 16, len(h.buckets))
+
+	h, _ = newHtable[string, int](24)
+	assert.Equal(t, 32, len(h.buckets))
+}
+
+func TestNewHtableError(t *testing.T) {
+	_, err := newHtable[string, int](-1)
+	assert.Error(t, err)
+}
+
+func TestHtableIndexFor(t *testing.T) {
+	ht := &htable[string, int]{
+		mask: 3,
+	}
+	assert.Equal(t, 1, ht.indexFor(1))
+	assert.Equal(t, 0, ht.indexFor(4))
+	assert.Equal(t, 3, ht.indexFor(7))
+}
@@ -0,0 +1,9 @@
+package v2
+
+// Map is the type-safe counterpart of ccmap.Map. Keys are compared with ==
+// and hashed by a Hasher chosen for K, values are stored without boxing.
+type Map[K comparable, V any] interface {
+	Put(k K, val V) bool
+	Get(k K) (V, bool)
+	Delete(k K) bool
+}